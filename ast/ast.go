@@ -3,6 +3,7 @@ package ast
 import (
 	"bytes"
 	"fmt"
+	"math/big"
 	"strings"
 
 	"git.tigh.dev/tigh-latte/monkeyscript/token"
@@ -23,6 +24,12 @@ type Expression interface {
 	expressionNode()
 }
 
+// Pattern is the left hand side of a destructuring binding.
+type Pattern interface {
+	Node
+	patternNode()
+}
+
 type Program struct {
 	Statements []Statement
 }
@@ -31,12 +38,24 @@ func (p *Program) String() string {
 	bb := new(bytes.Buffer)
 
 	for _, s := range p.Statements {
-		bb.WriteString(s.String())
+		writeStatement(bb, s)
 	}
 
 	return bb.String()
 }
 
+// writeStatement writes s to bb. A block among statements is a standalone
+// block, so it is written in braces to keep its scope visible.
+func writeStatement(bb *bytes.Buffer, s Statement) {
+	if block, ok := s.(*BlockStatement); ok {
+		bb.WriteString("{ ")
+		bb.WriteString(block.String())
+		bb.WriteString(" }")
+		return
+	}
+	bb.WriteString(s.String())
+}
+
 func (p *Program) TokenLiteral() string {
 	if len(p.Statements) > 0 {
 		return p.Statements[0].TokenLiteral()
@@ -46,16 +65,26 @@ func (p *Program) TokenLiteral() string {
 }
 
 type LetStatement struct {
-	Token token.Token // `token.LET`
-	Name  *Identifier
-	Value Expression
+	Token   token.Token // `token.LET` or `token.CONST`
+	Name    *Identifier
+	Pattern Pattern // set instead of Name when destructuring
+	Value   Expression
+}
+
+// IsConst reports whether the statement declares a constant.
+func (l *LetStatement) IsConst() bool {
+	return l.Token.Type == token.CONST
 }
 
 func (l *LetStatement) String() string {
 	bb := new(bytes.Buffer)
 
 	bb.WriteString(l.TokenLiteral() + " ")
-	bb.WriteString(l.Name.String())
+	if l.Pattern != nil {
+		bb.WriteString(l.Pattern.String())
+	} else {
+		bb.WriteString(l.Name.String())
+	}
 	bb.WriteString(" = ")
 	if l.Value != nil {
 		bb.WriteString(l.Value.String())
@@ -76,6 +105,7 @@ type Identifier struct {
 }
 
 func (i *Identifier) expressionNode() {}
+func (i *Identifier) patternNode()    {}
 func (i *Identifier) TokenLiteral() string {
 	return i.Token.Literal
 }
@@ -87,6 +117,7 @@ func (i *Identifier) String() string {
 type IntegerLiteral struct {
 	Token token.Token // `token.INT`
 	Value int64
+	Big   *big.Int // the value instead of Value, when it overflows int64
 }
 
 func (i *IntegerLiteral) expressionNode() {}
@@ -98,6 +129,22 @@ func (i *IntegerLiteral) String() string {
 	return i.Token.Literal
 }
 
+// RationalLiteral is a number literal with an exponent, such as `25e-2`,
+// whose value is not whole. Whole ones, such as `1e3`, are IntegerLiterals.
+type RationalLiteral struct {
+	Token token.Token // `token.INT`
+	Value *big.Rat
+}
+
+func (r *RationalLiteral) expressionNode() {}
+func (r *RationalLiteral) TokenLiteral() string {
+	return r.Token.Literal
+}
+
+func (r *RationalLiteral) String() string {
+	return r.Token.Literal
+}
+
 type ReturnStatement struct {
 	Token       token.Token // `token.RETURN`
 	ReturnValue Expression
@@ -196,6 +243,19 @@ func (b *Boolean) String() string {
 	return b.Token.Literal
 }
 
+type NullLiteral struct {
+	Token token.Token
+}
+
+func (n *NullLiteral) expressionNode() {}
+func (n *NullLiteral) TokenLiteral() string {
+	return n.Token.Literal
+}
+
+func (n *NullLiteral) String() string {
+	return n.Token.Literal
+}
+
 type IfExpression struct {
 	Token       token.Token
 	Condition   Expression
@@ -238,7 +298,7 @@ func (b *BlockStatement) String() string {
 	bb := new(bytes.Buffer)
 
 	for _, s := range b.Statements {
-		bb.WriteString(s.String())
+		writeStatement(bb, s)
 	}
 
 	return bb.String()
@@ -246,7 +306,10 @@ func (b *BlockStatement) String() string {
 
 type FunctionLiteral struct {
 	Token      token.Token
+	Name       string // set for declarations and `let` bound literals
 	Parameters []*Identifier
+	Defaults   map[string]Expression // default values, keyed by parameter name
+	Rest       *Identifier           // trailing `...rest` parameter, if any
 	Body       *BlockStatement
 }
 
@@ -258,14 +321,26 @@ func (f *FunctionLiteral) TokenLiteral() string {
 func (f *FunctionLiteral) String() string {
 	bb := new(bytes.Buffer)
 
-	bb.WriteString(f.TokenLiteral())
-	bb.WriteString("(")
-
 	params := make([]string, len(f.Parameters))
 	for i, param := range f.Parameters {
 		params[i] = param.String()
+		if def, ok := f.Defaults[param.Value]; ok {
+			params[i] += " = " + def.String()
+		}
+	}
+	if f.Rest != nil {
+		params = append(params, "..."+f.Rest.String())
 	}
 
+	// Lambdas, written `|x| body` or `x => body`, print in the `|x|` form.
+	if f.Token.Type != token.FUNCTION {
+		bb.WriteString("|" + strings.Join(params, ", ") + "| ")
+		bb.WriteString(f.Body.String())
+		return bb.String()
+	}
+
+	bb.WriteString(f.TokenLiteral())
+	bb.WriteString("(")
 	bb.WriteString(strings.Join(params, ", "))
 	bb.WriteString(") {")
 	bb.WriteString(f.Body.String())
@@ -274,10 +349,28 @@ func (f *FunctionLiteral) String() string {
 	return bb.String()
 }
 
+// PipeExpression is `Left |> Right`. When Right is a call, Left is passed as
+// its first argument; otherwise Right is called with Left alone.
+type PipeExpression struct {
+	Token token.Token // The '|>' token
+	Left  Expression
+	Right Expression
+}
+
+func (p *PipeExpression) expressionNode() {}
+func (p *PipeExpression) TokenLiteral() string {
+	return p.Token.Literal
+}
+
+func (p *PipeExpression) String() string {
+	return "(" + p.Left.String() + " |> " + p.Right.String() + ")"
+}
+
 type CallExpression struct {
 	Token     token.Token // Then '(' token
-	Function  Expression  // Identifier or FunctionLiteral
+	Function  Expression  // Identifier, FunctionLiteral or DotExpression
 	Arguments []Expression
+	Keywords  []*KeywordArgument // named arguments, which follow the positional ones
 }
 
 func (c *CallExpression) expressionNode() {}
@@ -288,9 +381,12 @@ func (c *CallExpression) TokenLiteral() string {
 func (c *CallExpression) String() string {
 	bb := new(bytes.Buffer)
 
-	args := make([]string, len(c.Arguments))
-	for i, a := range c.Arguments {
-		args[i] = a.String()
+	args := make([]string, 0, len(c.Arguments)+len(c.Keywords))
+	for _, a := range c.Arguments {
+		args = append(args, a.String())
+	}
+	for _, kw := range c.Keywords {
+		args = append(args, kw.String())
 	}
 
 	bb.WriteString(c.Function.String())
@@ -301,6 +397,16 @@ func (c *CallExpression) String() string {
 	return bb.String()
 }
 
+type KeywordArgument struct {
+	Token token.Token // the name's token
+	Name  *Identifier
+	Value Expression
+}
+
+func (ka *KeywordArgument) expressionNode()      {}
+func (ka *KeywordArgument) TokenLiteral() string { return ka.Token.Literal }
+func (ka *KeywordArgument) String() string       { return ka.Name.String() + ": " + ka.Value.String() }
+
 type StringLiteral struct {
 	Token token.Token
 	Value string
@@ -310,6 +416,44 @@ func (sl *StringLiteral) expressionNode()      {}
 func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
 func (sl *StringLiteral) String() string       { return sl.Token.Literal }
 
+// InterpolatedString is a string literal with embedded expressions, e.g.
+// `"Total: ${total:.2f} for ${customer.name}"`.
+type InterpolatedString struct {
+	Token token.Token // the `token.INTERPSTART` token
+	Head  string      // the text before the first interpolation
+	Parts []Interpolation
+}
+
+// Interpolation is an expression embedded in an InterpolatedString, along with
+// the text which follows it.
+type Interpolation struct {
+	Value  Expression
+	Format string // the spec written after ':', if any
+	Text   string
+}
+
+func (is *InterpolatedString) expressionNode()      {}
+func (is *InterpolatedString) TokenLiteral() string { return is.Token.Literal }
+func (is *InterpolatedString) String() string {
+	bb := new(bytes.Buffer)
+
+	bb.WriteRune('"')
+	bb.WriteString(is.Head)
+	for _, part := range is.Parts {
+		bb.WriteString("${")
+		bb.WriteString(part.Value.String())
+		if part.Format != "" {
+			bb.WriteRune(':')
+			bb.WriteString(part.Format)
+		}
+		bb.WriteRune('}')
+		bb.WriteString(part.Text)
+	}
+	bb.WriteRune('"')
+
+	return bb.String()
+}
+
 type ArrayLiteral struct {
 	Token    token.Token
 	Elements []Expression
@@ -332,10 +476,35 @@ func (al *ArrayLiteral) String() string {
 	return bb.String()
 }
 
+// DotExpression is `Left.Name`: a field of a hash, or a method when called.
+// `Left?.Name` is Optional, and evaluates to null when Left is null.
+type DotExpression struct {
+	Token    token.Token // The '.' or '?.' token
+	Left     Expression
+	Name     *Identifier
+	Optional bool
+}
+
+func (d *DotExpression) expressionNode() {}
+func (d *DotExpression) TokenLiteral() string {
+	return d.Token.Literal
+}
+
+func (d *DotExpression) String() string {
+	return d.Left.String() + d.TokenLiteral() + d.Name.String()
+}
+
 type IndexExpression struct {
 	Token token.Token
 	Left  Expression
 	Index Expression
+
+	// Slice is set for `left[index:end]`, where either bound may be nil.
+	Slice bool
+	End   Expression
+
+	// Optional is set for `left?[index]`, which is null when left is null.
+	Optional bool
 }
 
 func (ie *IndexExpression) expressionNode()      {}
@@ -345,16 +514,30 @@ func (ie *IndexExpression) String() string {
 
 	bb.WriteByte('(')
 	bb.WriteString(ie.Left.String())
-	bb.WriteByte('[')
-	bb.WriteString(ie.Index.String())
+	bb.WriteString(ie.TokenLiteral())
+	if ie.Index != nil {
+		bb.WriteString(ie.Index.String())
+	}
+	if ie.Slice {
+		bb.WriteByte(':')
+		if ie.End != nil {
+			bb.WriteString(ie.End.String())
+		}
+	}
 	bb.WriteString("])")
 
 	return bb.String()
 }
 
+// HashPair is one `key: value` entry of a HashLiteral.
+type HashPair struct {
+	Key   Expression
+	Value Expression
+}
+
 type HashLiteral struct {
 	Token token.Token // the '{' character
-	Pairs map[Expression]Expression
+	Pairs []HashPair  // in the order written
 }
 
 func (hl *HashLiteral) expressionNode()      {}
@@ -362,9 +545,9 @@ func (hl *HashLiteral) TokenLiteral() string { return hl.Token.Literal }
 func (hl *HashLiteral) String() string {
 	bb := new(bytes.Buffer)
 
-	pairs := make([]string, len(hl.Pairs))
-	for k, v := range hl.Pairs {
-		pairs = append(pairs, k.String()+":"+v.String())
+	pairs := make([]string, 0, len(hl.Pairs))
+	for _, pair := range hl.Pairs {
+		pairs = append(pairs, pair.Key.String()+":"+pair.Value.String())
 	}
 
 	bb.WriteRune('{')
@@ -373,3 +556,336 @@ func (hl *HashLiteral) String() string {
 
 	return bb.String()
 }
+
+type AssignExpression struct {
+	Token  token.Token // the '=' token
+	Target Expression  // Identifier, IndexExpression or DotExpression
+	Value  Expression
+}
+
+func (ae *AssignExpression) expressionNode()      {}
+func (ae *AssignExpression) TokenLiteral() string { return ae.Token.Literal }
+func (ae *AssignExpression) String() string {
+	bb := new(bytes.Buffer)
+
+	bb.WriteByte('(')
+	bb.WriteString(ae.Target.String())
+	bb.WriteString(" = ")
+	bb.WriteString(ae.Value.String())
+	bb.WriteByte(')')
+
+	return bb.String()
+}
+
+type WhileStatement struct {
+	Token     token.Token // the 'while' token
+	Condition Expression
+	Body      *BlockStatement
+}
+
+func (ws *WhileStatement) statementNode()       {}
+func (ws *WhileStatement) TokenLiteral() string { return ws.Token.Literal }
+func (ws *WhileStatement) String() string {
+	bb := new(bytes.Buffer)
+
+	bb.WriteString("while")
+	bb.WriteString(ws.Condition.String())
+	bb.WriteString(" ")
+	bb.WriteString(ws.Body.String())
+
+	return bb.String()
+}
+
+type BreakStatement struct {
+	Token token.Token // the 'break' token
+}
+
+func (bs *BreakStatement) statementNode()       {}
+func (bs *BreakStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BreakStatement) String() string       { return bs.Token.Literal + ";" }
+
+type ContinueStatement struct {
+	Token token.Token // the 'continue' token
+}
+
+func (cs *ContinueStatement) statementNode()       {}
+func (cs *ContinueStatement) TokenLiteral() string { return cs.Token.Literal }
+func (cs *ContinueStatement) String() string       { return cs.Token.Literal + ";" }
+
+type ForStatement struct {
+	Token    token.Token   // the 'for' token
+	Vars     []*Identifier // one or two loop variables
+	Iterable Expression
+	Body     *BlockStatement
+}
+
+func (fs *ForStatement) statementNode()       {}
+func (fs *ForStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs *ForStatement) String() string {
+	bb := new(bytes.Buffer)
+
+	vars := make([]string, len(fs.Vars))
+	for i, v := range fs.Vars {
+		vars[i] = v.String()
+	}
+
+	bb.WriteString("for (")
+	bb.WriteString(strings.Join(vars, ", "))
+	bb.WriteString(" in ")
+	bb.WriteString(fs.Iterable.String())
+	bb.WriteString(") ")
+	bb.WriteString(fs.Body.String())
+
+	return bb.String()
+}
+
+type RangeExpression struct {
+	Token token.Token // the '..' or '..<' token
+	Start Expression
+	End   Expression
+	Step  Expression // optional
+}
+
+func (re *RangeExpression) expressionNode()      {}
+func (re *RangeExpression) TokenLiteral() string { return re.Token.Literal }
+func (re *RangeExpression) String() string {
+	bb := new(bytes.Buffer)
+
+	bb.WriteByte('(')
+	bb.WriteString(re.Start.String())
+	bb.WriteString(re.Token.Literal)
+	bb.WriteString(re.End.String())
+	if re.Step != nil {
+		bb.WriteString(" step ")
+		bb.WriteString(re.Step.String())
+	}
+	bb.WriteByte(')')
+
+	return bb.String()
+}
+
+type FunctionStatement struct {
+	Token    token.Token // the 'fn' token
+	Name     *Identifier
+	Function *FunctionLiteral
+}
+
+func (fs *FunctionStatement) statementNode()       {}
+func (fs *FunctionStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs *FunctionStatement) String() string {
+	bb := new(bytes.Buffer)
+
+	bb.WriteString(fs.TokenLiteral() + " ")
+	bb.WriteString(fs.Name.String())
+	// Drop the literal's own 'fn' so the declaration reads `fn name(...)`.
+	bb.WriteString(strings.TrimPrefix(fs.Function.String(), fs.Function.TokenLiteral()))
+
+	return bb.String()
+}
+
+type SpreadExpression struct {
+	Token token.Token // the '...' token
+	Value Expression
+}
+
+func (se *SpreadExpression) expressionNode()      {}
+func (se *SpreadExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SpreadExpression) String() string       { return "..." + se.Value.String() }
+
+type ArrayPattern struct {
+	Token    token.Token // the '[' token
+	Elements []Pattern
+	Rest     *Identifier // trailing `...rest`, if any
+}
+
+func (ap *ArrayPattern) patternNode()         {}
+func (ap *ArrayPattern) TokenLiteral() string { return ap.Token.Literal }
+func (ap *ArrayPattern) String() string {
+	bb := new(bytes.Buffer)
+
+	elems := make([]string, 0, len(ap.Elements)+1)
+	for _, el := range ap.Elements {
+		elems = append(elems, el.String())
+	}
+	if ap.Rest != nil {
+		elems = append(elems, "..."+ap.Rest.String())
+	}
+
+	bb.WriteByte('[')
+	bb.WriteString(strings.Join(elems, ", "))
+	bb.WriteByte(']')
+
+	return bb.String()
+}
+
+type HashPatternEntry struct {
+	Key     string
+	Pattern Pattern // the identifier named Key unless renamed or nested
+}
+
+func (e *HashPatternEntry) String() string {
+	if ident, ok := e.Pattern.(*Identifier); ok && ident.Value == e.Key {
+		return e.Key
+	}
+	if def, ok := e.Pattern.(*DefaultPattern); ok {
+		if ident, ok := def.Pattern.(*Identifier); ok && ident.Value == e.Key {
+			return def.String()
+		}
+	}
+	return e.Key + ": " + e.Pattern.String()
+}
+
+type HashPattern struct {
+	Token   token.Token // the '{' token
+	Entries []*HashPatternEntry
+	Rest    *Identifier // trailing `...rest`, if any
+}
+
+func (hp *HashPattern) patternNode()         {}
+func (hp *HashPattern) TokenLiteral() string { return hp.Token.Literal }
+func (hp *HashPattern) String() string {
+	bb := new(bytes.Buffer)
+
+	entries := make([]string, 0, len(hp.Entries)+1)
+	for _, e := range hp.Entries {
+		entries = append(entries, e.String())
+	}
+	if hp.Rest != nil {
+		entries = append(entries, "..."+hp.Rest.String())
+	}
+
+	bb.WriteRune('{')
+	bb.WriteString(strings.Join(entries, ", "))
+	bb.WriteRune('}')
+
+	return bb.String()
+}
+
+// DefaultPattern supplies a value for an array element or hash key which is
+// missing from the value being destructured.
+type DefaultPattern struct {
+	Token   token.Token // the '=' token
+	Pattern Pattern
+	Default Expression
+}
+
+func (dp *DefaultPattern) patternNode()         {}
+func (dp *DefaultPattern) TokenLiteral() string { return dp.Token.Literal }
+func (dp *DefaultPattern) String() string {
+	return dp.Pattern.String() + " = " + dp.Default.String()
+}
+
+// LiteralPattern matches values equal to a literal, or integers within a
+// range literal.
+type LiteralPattern struct {
+	Token token.Token // the literal's first token
+	Value Expression
+}
+
+func (lp *LiteralPattern) patternNode()         {}
+func (lp *LiteralPattern) TokenLiteral() string { return lp.Token.Literal }
+func (lp *LiteralPattern) String() string       { return lp.Value.String() }
+
+// TypePattern matches values of the named type, binding them to Name.
+type TypePattern struct {
+	Token    token.Token // the ':' token
+	Name     *Identifier
+	TypeName *Identifier
+}
+
+func (tp *TypePattern) patternNode()         {}
+func (tp *TypePattern) TokenLiteral() string { return tp.Token.Literal }
+func (tp *TypePattern) String() string       { return tp.Name.String() + ": " + tp.TypeName.String() }
+
+type MatchArm struct {
+	Pattern Pattern
+	Guard   Expression // optional `if` condition
+	Body    *BlockStatement
+}
+
+func (ma *MatchArm) String() string {
+	bb := new(bytes.Buffer)
+
+	bb.WriteString(ma.Pattern.String())
+	if ma.Guard != nil {
+		bb.WriteString(" if ")
+		bb.WriteString(ma.Guard.String())
+	}
+	bb.WriteString(" => ")
+	bb.WriteString(ma.Body.String())
+
+	return bb.String()
+}
+
+type MatchExpression struct {
+	Token   token.Token // the 'match' token
+	Subject Expression
+	Arms    []*MatchArm
+}
+
+func (me *MatchExpression) expressionNode()      {}
+func (me *MatchExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MatchExpression) String() string {
+	bb := new(bytes.Buffer)
+
+	arms := make([]string, len(me.Arms))
+	for i, arm := range me.Arms {
+		arms[i] = arm.String()
+	}
+
+	bb.WriteString("match (")
+	bb.WriteString(me.Subject.String())
+	bb.WriteString(") { ")
+	bb.WriteString(strings.Join(arms, ", "))
+	bb.WriteString(" }")
+
+	return bb.String()
+}
+
+type ThrowStatement struct {
+	Token token.Token // the 'throw' token
+	Value Expression
+}
+
+func (ts *ThrowStatement) statementNode()       {}
+func (ts *ThrowStatement) TokenLiteral() string { return ts.Token.Literal }
+func (ts *ThrowStatement) String() string {
+	return "throw " + ts.Value.String() + ";"
+}
+
+// TryExpression is `try { } catch (e) { } finally { }`, where either the
+// catch or the finally clause may be left out, and the catch clause need not
+// name the error.
+type TryExpression struct {
+	Token   token.Token // the 'try' token
+	Block   *BlockStatement
+	Param   *Identifier
+	Catch   *BlockStatement
+	Finally *BlockStatement
+}
+
+func (te *TryExpression) expressionNode()      {}
+func (te *TryExpression) TokenLiteral() string { return te.Token.Literal }
+func (te *TryExpression) String() string {
+	bb := new(bytes.Buffer)
+
+	bb.WriteString("try { ")
+	bb.WriteString(te.Block.String())
+	bb.WriteString(" }")
+	if te.Catch != nil {
+		bb.WriteString(" catch ")
+		if te.Param != nil {
+			bb.WriteString("(" + te.Param.String() + ") ")
+		}
+		bb.WriteString("{ ")
+		bb.WriteString(te.Catch.String())
+		bb.WriteString(" }")
+	}
+	if te.Finally != nil {
+		bb.WriteString(" finally { ")
+		bb.WriteString(te.Finally.String())
+		bb.WriteString(" }")
+	}
+
+	return bb.String()
+}
@@ -0,0 +1,107 @@
+package evaluator
+
+import (
+	"math"
+	"math/big"
+
+	"git.tigh.dev/tigh-latte/monkeyscript/object"
+)
+
+// evalIntegerArithmetic applies one of + - * / to two int64s. What happens
+// when the result overflows depends on mode: it wraps, fails with an
+// OverflowError, or is promoted to a BigInt. right must not be zero for /.
+func evalIntegerArithmetic(operator string, left, right int64, mode object.ArithmeticMode) object.Object {
+	var res int64
+	var overflow bool
+	switch operator {
+	case "+":
+		res = left + right
+		overflow = right > 0 && res < left || right < 0 && res > left
+	case "-":
+		res = left - right
+		overflow = right > 0 && res > left || right < 0 && res < left
+	case "*":
+		res = left * right
+		overflow = left != 0 && (res/left != right || left == -1 && right == math.MinInt64)
+	case "/":
+		res = left / right
+		overflow = left == math.MinInt64 && right == -1
+	}
+
+	if !overflow || mode == object.WrapArithmetic {
+		return &object.Integer{Value: res}
+	}
+	if mode == object.CheckedArithmetic {
+		return newErrorf(object.OverflowError, "integer overflow: %d %s %d", left, operator, right)
+	}
+	return evalBigIntInfixExpression(operator, &object.Integer{Value: left}, &object.Integer{Value: right})
+}
+
+// evalIntegerNegation negates n. Only math.MinInt64 overflows; it is handled
+// according to mode, as for evalIntegerArithmetic.
+func evalIntegerNegation(n int64, mode object.ArithmeticMode) object.Object {
+	if n != math.MinInt64 || mode == object.WrapArithmetic {
+		return &object.Integer{Value: -n}
+	}
+	if mode == object.CheckedArithmetic {
+		return newErrorf(object.OverflowError, "integer overflow: -(%d)", n)
+	}
+	return object.NewInteger(new(big.Int).Neg(big.NewInt(n)))
+}
+
+// evalBigIntInfixExpression evaluates an operator between two integers at
+// least one of which is a BigInt, or which overflow as int64s.
+func evalBigIntInfixExpression(operator string, lObj, rObj object.Object) object.Object {
+	left, _ := object.ToBig(lObj)
+	right, _ := object.ToBig(rObj)
+
+	switch operator {
+	case "+":
+		return object.NewInteger(new(big.Int).Add(left, right))
+	case "-":
+		return object.NewInteger(new(big.Int).Sub(left, right))
+	case "*":
+		return object.NewInteger(new(big.Int).Mul(left, right))
+	case "/":
+		if right.Sign() == 0 {
+			return newErrorf(object.DivisionByZero, "division by zero: %s / 0", left)
+		}
+		// Quo truncates towards zero, as int64 division does.
+		return object.NewInteger(new(big.Int).Quo(left, right))
+	case "<":
+		return evalBoolean(left.Cmp(right) < 0)
+	case ">":
+		return evalBoolean(left.Cmp(right) > 0)
+	case "==":
+		return evalBoolean(left.Cmp(right) == 0)
+	case "!=":
+		return evalBoolean(left.Cmp(right) != 0)
+	default:
+		return newErrorf(object.TypeError, "unknown operator: %s %s %s", lObj.Type(), operator, rObj.Type())
+	}
+}
+
+// integerInMode returns n as an Integer if it fits in int64. Otherwise what
+// happens depends on mode, as for evalIntegerArithmetic: n wraps to int64, is
+// a BigInt, or under CheckedArithmetic ok is false.
+func integerInMode(n *big.Int, mode object.ArithmeticMode) (res object.Object, ok bool) {
+	switch {
+	case n.IsInt64():
+		return &object.Integer{Value: n.Int64()}, true
+	case mode == object.WrapArithmetic:
+		// And treats n as two's complement, keeping its low 64 bits.
+		low := new(big.Int).And(n, new(big.Int).SetUint64(math.MaxUint64))
+		return &object.Integer{Value: int64(low.Uint64())}, true
+	case mode == object.BigArithmetic:
+		return &object.BigInt{Value: n}, true
+	}
+	return nil, false
+}
+
+func isInteger(obj object.Object) bool {
+	switch obj.(type) {
+	case *object.Integer, *object.BigInt:
+		return true
+	}
+	return false
+}
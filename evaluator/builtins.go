@@ -1,31 +1,49 @@
 package evaluator
 
 import (
+	"bytes"
 	"fmt"
+	"slices"
+	"unicode/utf8"
 
 	"git.tigh.dev/tigh-latte/monkeyscript/object"
 )
 
+func init() {
+	for name, builtin := range builtins {
+		builtin.Name = name
+	}
+	for _, byName := range methods {
+		for name, method := range byName {
+			if method.Name == "" {
+				method.Name = name
+			}
+		}
+	}
+}
+
 var builtins = map[string]*object.Builtin{
 	"len": {
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return newErrorf("wrong number of arguments. got=%d, want=1", len(args))
+				return newErrorf(object.ArityError, "wrong number of arguments. got=%d, want=1", len(args))
 			}
 			switch arg := args[0].(type) {
 			case *object.String:
-				return &object.Integer{Value: int64(len(arg.Value))}
+				return &object.Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
 			case *object.Array:
 				return &object.Integer{Value: int64(len(arg.Elements))}
+			case *object.Range:
+				return &object.Integer{Value: arg.Len()}
 			default:
-				return newErrorf("argument to `len` not supported, got %s", arg.Type())
+				return newErrorf(object.TypeError, "argument to `len` not supported, got %s", arg.Type())
 			}
 		},
 	},
 	"first": {
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return newErrorf("wrong number of arguments. got=%d, want=1", len(args))
+				return newErrorf(object.ArityError, "wrong number of arguments. got=%d, want=1", len(args))
 			}
 
 			switch arg := args[0].(type) {
@@ -35,14 +53,14 @@ var builtins = map[string]*object.Builtin{
 				}
 				return arg.Elements[0]
 			default:
-				return newErrorf("argument to `first` not supported, got %s", arg.Type())
+				return newErrorf(object.TypeError, "argument to `first` not supported, got %s", arg.Type())
 			}
 		},
 	},
 	"last": {
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return newErrorf("wrong number of arguments. got=%d, want=1", len(args))
+				return newErrorf(object.ArityError, "wrong number of arguments. got=%d, want=1", len(args))
 			}
 
 			switch arg := args[0].(type) {
@@ -52,14 +70,14 @@ var builtins = map[string]*object.Builtin{
 				}
 				return arg.Elements[len(arg.Elements)-1]
 			default:
-				return newErrorf("argument to `first` not supported, got %s", arg.Type())
+				return newErrorf(object.TypeError, "argument to `first` not supported, got %s", arg.Type())
 			}
 		},
 	},
 	"rest": {
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return newErrorf("wrong number of arguments. got=%d, want=1", len(args))
+				return newErrorf(object.ArityError, "wrong number of arguments. got=%d, want=1", len(args))
 			}
 
 			switch arg := args[0].(type) {
@@ -71,14 +89,14 @@ var builtins = map[string]*object.Builtin{
 				copy(cpy, arg.Elements[1:])
 				return &object.Array{Elements: cpy}
 			default:
-				return newErrorf("argument to `rest` not supported, got %s", arg.Type())
+				return newErrorf(object.TypeError, "argument to `rest` not supported, got %s", arg.Type())
 			}
 		},
 	},
 	"push": {
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 2 {
-				return newErrorf("wrong number of arguments. got=%d, want=2", len(args))
+				return newErrorf(object.ArityError, "wrong number of arguments. got=%d, want=2", len(args))
 			}
 
 			switch arg := args[0].(type) {
@@ -88,10 +106,160 @@ var builtins = map[string]*object.Builtin{
 				cpy[len(arg.Elements)] = args[1]
 				return &object.Array{Elements: cpy}
 			default:
-				return newErrorf("argument to `push` not supported, go %s", arg.Type())
+				return newErrorf(object.TypeError, "argument to `push` not supported, go %s", arg.Type())
+			}
+		},
+	},
+	"append!": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) < 1 {
+				return newErrorf(object.ArityError, "wrong number of arguments. got=%d, want>=1", len(args))
+			}
+
+			switch arg := args[0].(type) {
+			case *object.Array:
+				if err := checkMutable(arg); err != nil {
+					return err
+				}
+				arg.Elements = append(arg.Elements, args[1:]...)
+				return arg
+			default:
+				return newErrorf(object.TypeError, "argument to `append!` not supported, got %s", arg.Type())
+			}
+		},
+	},
+	"set!": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 3 {
+				return newErrorf(object.ArityError, "wrong number of arguments. got=%d, want=3", len(args))
+			}
+
+			switch arg := args[0].(type) {
+			case *object.Array, *object.Hash:
+				if res := evalIndexAssignment(arg, args[1], args[2]); isError(res) {
+					return res
+				}
+				return arg
+			default:
+				return newErrorf(object.TypeError, "argument to `set!` not supported, got %s", arg.Type())
+			}
+		},
+	},
+	"delete!": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newErrorf(object.ArityError, "wrong number of arguments. got=%d, want=2", len(args))
+			}
+			if err := checkMutable(args[0]); err != nil {
+				return err
+			}
+
+			switch arg := args[0].(type) {
+			case *object.Array:
+				if args[1].Type() == object.BigIntType {
+					return bigIndexError(args[1], arg)
+				}
+				integer, ok := args[1].(*object.Integer)
+				if !ok {
+					return newErrorf(object.TypeError, "array index must be INTEGER, got %s", args[1].Type())
+				}
+				idx, ok := normaliseIndex(integer.Value, len(arg.Elements))
+				if !ok {
+					return newErrorf(object.IndexError, "index out of range: %d with length %d", integer.Value, len(arg.Elements))
+				}
+				arg.Elements = append(arg.Elements[:idx], arg.Elements[idx+1:]...)
+				return arg
+			case *object.Hash:
+				key, ok := object.KeyOf(args[1])
+				if !ok {
+					return newErrorf(object.TypeError, "unusable as hash key: %s", args[1].Type())
+				}
+				arg.Delete(key)
+				return arg
+			default:
+				return newErrorf(object.TypeError, "argument to `delete!` not supported, got %s", arg.Type())
+			}
+		},
+	},
+	"keys": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newErrorf(object.ArityError, "wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			switch arg := args[0].(type) {
+			case *object.Hash:
+				pairs := arg.Pairs()
+				keys := make([]object.Object, len(pairs))
+				for i, pair := range pairs {
+					keys[i] = pair.Key
+				}
+				return &object.Array{Elements: keys}
+			default:
+				return newErrorf(object.TypeError, "argument to `keys` not supported, got %s", arg.Type())
+			}
+		},
+	},
+	"values": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newErrorf(object.ArityError, "wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			switch arg := args[0].(type) {
+			case *object.Hash:
+				pairs := arg.Pairs()
+				values := make([]object.Object, len(pairs))
+				for i, pair := range pairs {
+					values[i] = pair.Value
+				}
+				return &object.Array{Elements: values}
+			default:
+				return newErrorf(object.TypeError, "argument to `values` not supported, got %s", arg.Type())
+			}
+		},
+	},
+	"sort": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newErrorf(object.ArityError, "wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			switch arg := args[0].(type) {
+			case *object.Array:
+				sorted := slices.Clone(arg.Elements)
+				slices.SortStableFunc(sorted, object.Compare)
+				return &object.Array{Elements: sorted}
+			default:
+				return newErrorf(object.TypeError, "argument to `sort` not supported, got %s", arg.Type())
+			}
+		},
+	},
+	"freeze": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newErrorf(object.ArityError, "wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			freeze(args[0])
+			return args[0]
+		},
+	},
+	"json": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newErrorf(object.ArityError, "wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			bb := new(bytes.Buffer)
+			if err := encodeJSON(bb, args[0], map[object.Object]bool{}); err != nil {
+				return err
 			}
+			return &object.String{Value: bb.String()}
 		},
 	},
+	"ratio": {Fn: numberRatio},
+	"round": {KwFn: numberRound},
 	"puts": {
 		Fn: func(args ...object.Object) object.Object {
 			for _, arg := range args {
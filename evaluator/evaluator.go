@@ -1,18 +1,41 @@
 package evaluator
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"math/big"
+	"slices"
+	"unicode/utf8"
 
 	"git.tigh.dev/tigh-latte/monkeyscript/ast"
 	"git.tigh.dev/tigh-latte/monkeyscript/object"
+	"git.tigh.dev/tigh-latte/monkeyscript/token"
 )
 
 var (
 	Null  = &object.Null{}
 	True  = &object.Boolean{Value: true}
 	False = &object.Boolean{Value: false}
+
+	breakSignal    = &object.Break{}
+	continueSignal = &object.Continue{}
 )
 
+// Run evaluates program in env like Eval, but returns a runtime error as a Go
+// error, so hosts can handle it with errors.Is and errors.As:
+//
+//	if _, err := evaluator.Run(program, env); errors.Is(err, object.TypeError) {
+//		...
+//	}
+func Run(program *ast.Program, env *object.Environment) (object.Object, error) {
+	result := Eval(program, env)
+	if err, ok := result.(*object.Error); ok {
+		return nil, err
+	}
+	return result, nil
+}
+
 func Eval(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 	case *ast.Program:
@@ -20,32 +43,71 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.ExpressionStatement:
 		return Eval(node.Expression, env)
 	case *ast.IntegerLiteral:
+		if node.Big != nil {
+			if env.Arithmetic() != object.BigArithmetic {
+				return newErrorfAt(node.Token.Pos, object.OverflowError, "integer literal overflows int64: %s", node.Token.Literal)
+			}
+			return &object.BigInt{Value: node.Big}
+		}
 		return &object.Integer{Value: node.Value}
+	case *ast.RationalLiteral:
+		return &object.Rational{Value: node.Value}
 	case *ast.StringLiteral:
 		return &object.String{Value: node.Value}
+	case *ast.InterpolatedString:
+		return evalInterpolatedString(node, env)
 	case *ast.Boolean:
 		return evalBoolean(node.Value)
+	case *ast.NullLiteral:
+		return Null
 	case *ast.PrefixExpression:
 		right := Eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
-		return evalPrefixExpression(node.Operator, right)
+		return withPos(evalPrefixExpression(node.Operator, right, env.Arithmetic()), node.Token.Pos)
 	case *ast.InfixExpression:
 		left := Eval(node.Left, env)
 		if isError(left) {
 			return left
 		}
+		// `a ?? b` only evaluates b when a is null.
+		if node.Operator == "??" {
+			if left != Null {
+				return left
+			}
+			return Eval(node.Right, env)
+		}
 		right := Eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
 
-		return evalInfixExpression(node.Operator, left, right)
+		return withPos(evalInfixExpression(node.Operator, left, right, env.Arithmetic()), node.Token.Pos)
 	case *ast.BlockStatement:
 		return evalBlockStatement(node, env)
 	case *ast.IfExpression:
 		return evalIfExpression(node, env)
+	case *ast.MatchExpression:
+		return evalMatchExpression(node, env)
+	case *ast.TryExpression:
+		return evalTryExpression(node, env)
+	case *ast.ThrowStatement:
+		val := Eval(node.Value, env)
+		if isError(val) {
+			return val
+		}
+		return evalThrow(val, node.Token.Pos)
+	case *ast.WhileStatement:
+		return evalWhileStatement(node, env)
+	case *ast.ForStatement:
+		return evalForStatement(node, env)
+	case *ast.RangeExpression:
+		return withPos(evalRangeExpression(node, env), node.Token.Pos)
+	case *ast.BreakStatement:
+		return breakSignal
+	case *ast.ContinueStatement:
+		return continueSignal
 	case *ast.ReturnStatement:
 		val := Eval(node.ReturnValue, env)
 		if isError(val) {
@@ -57,7 +119,20 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		if isError(val) {
 			return val
 		}
-		return env.Set(node.Name.Value, val)
+		if node.Pattern != nil {
+			mismatch, err := bindPattern(node.Pattern, val, env)
+			if err != nil {
+				return err
+			}
+			if mismatch != nil {
+				return mismatch
+			}
+			return val
+		}
+		if err := declare(env, node.Name, val, node.IsConst()); err != nil {
+			return err
+		}
+		return val
 	case *ast.Identifier:
 		if val, ok := env.Get(node.Value); ok {
 			return val
@@ -66,82 +141,254 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			return builtin
 		}
 
-		return newError("identifier not found: " + node.Value)
+		return newErrorfAt(node.Token.Pos, object.NameError, "identifier not found: %s", node.Value)
 	case *ast.FunctionLiteral:
 		params := node.Parameters
 		body := node.Body
-		return &object.Function{Parameters: params, Env: env, Body: body}
-	case *ast.CallExpression:
-		fn := Eval(node.Function, env)
-		if isError(fn) {
-			return fn
+		return &object.Function{
+			Name:       node.Name,
+			Parameters: params,
+			Defaults:   node.Defaults,
+			Rest:       node.Rest,
+			Env:        env,
+			Body:       body,
 		}
-		args := evalExpressions(node.Arguments, env)
-		if len(args) == 1 && isError(args[0]) {
-			return args[0]
+	case *ast.FunctionStatement:
+		// Declarations are bound ahead of time by hoistFunctions.
+		if fn, ok := env.Get(node.Name.Value); ok {
+			return fn
 		}
-
-		return applyFunction(fn, args)
+		return Null
+	case *ast.CallExpression, *ast.DotExpression, *ast.IndexExpression:
+		res, _ := evalChain(node.(ast.Expression), env)
+		return res
+	case *ast.PipeExpression:
+		return evalPipeExpression(node, env)
 	case *ast.ArrayLiteral:
 		elems := evalExpressions(node.Elements, env)
 		if len(elems) == 1 && isError(elems[0]) {
 			return elems[0]
 		}
 		return &object.Array{Elements: elems}
-	case *ast.IndexExpression:
-		left := Eval(node.Left, env)
-		if isError(left) {
-			return left
-		}
-
-		index := Eval(node.Index, env)
-		if isError(index) {
-			return index
-		}
-		return evalIndexExpression(left, index)
 	case *ast.HashLiteral:
 		return evalHashLiteral(node, env)
+	case *ast.AssignExpression:
+		return evalAssignExpression(node, env)
+	case *ast.SpreadExpression:
+		return newErrorf(object.RuntimeError, "spread is only allowed in calls and array literals: %s", node)
 	}
 
-	return nil
+	return newErrorf(object.RuntimeError, "cannot evaluate %T", node)
 }
 
 func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Object {
-	result := make([]object.Object, len(exps))
+	result := make([]object.Object, 0, len(exps))
+
+	for _, e := range exps {
+		if spread, ok := e.(*ast.SpreadExpression); ok {
+			expanded := evalSpreadExpression(spread, env)
+			if len(expanded) == 1 && isError(expanded[0]) {
+				return expanded
+			}
+			result = append(result, expanded...)
+			continue
+		}
 
-	for i, e := range exps {
 		evaluated := Eval(e, env)
 		if isError(evaluated) {
 			return []object.Object{evaluated}
 		}
 
-		result[i] = evaluated
+		result = append(result, evaluated)
 	}
 
 	return result
 }
 
-func applyFunction(function object.Object, args []object.Object) object.Object {
+func evalSpreadExpression(spread *ast.SpreadExpression, env *object.Environment) []object.Object {
+	value := Eval(spread.Value, env)
+	if isError(value) {
+		return []object.Object{value}
+	}
+
+	switch value := value.(type) {
+	case *object.Array:
+		return value.Elements
+	case *object.Range:
+		elems := make([]object.Object, 0, value.Len())
+		for i := int64(0); i < value.Len(); i++ {
+			elems = append(elems, &object.Integer{Value: value.At(i)})
+		}
+		return elems
+	default:
+		return []object.Object{newErrorf(object.TypeError, "cannot spread %s", value.Type())}
+	}
+}
+
+func evalKeywords(kws []*ast.KeywordArgument, env *object.Environment) (object.Keywords, object.Object) {
+	if len(kws) == 0 {
+		return nil, nil
+	}
+
+	kwargs := make(object.Keywords, len(kws))
+	for i, kw := range kws {
+		value := Eval(kw.Value, env)
+		if isError(value) {
+			return nil, value
+		}
+		kwargs[i] = object.KeywordArgument{Name: kw.Name.Value, Value: value}
+	}
+
+	return kwargs, nil
+}
+
+// applyFunction calls function, called at pos, with args and kwargs. An error
+// raised within the function gains a frame on its stack trace for the call.
+func applyFunction(function object.Object, args []object.Object, kwargs object.Keywords, pos token.Position, mode object.ArithmeticMode) object.Object {
 	switch fn := function.(type) {
 	case *object.Function:
-		env := extendFunctionEnv(fn, args)
+		env, err := extendFunctionEnv(fn, args, kwargs)
+		if err != nil {
+			return withPos(err, pos)
+		}
+
+		if err := env.EnterCall(); err != nil {
+			return newErrorfAt(pos, object.RecursionError, "%s: %d", err, env.MaxCallDepth())
+		}
+		defer env.ExitCall()
+
 		evaluated := Eval(fn.Body, env)
+		if isLoopControl(evaluated) {
+			evaluated = newErrorf(object.RuntimeError, "%s outside of loop", evaluated.Inspect())
+		}
 
-		return unwrapReturnValue(evaluated)
+		return withFrame(unwrapReturnValue(evaluated), object.Frame{Function: fn.Name, Pos: pos})
 	case *object.Builtin:
+		res := callBuiltin(fn, args, kwargs)
+
+		// Builtins know nothing of the arithmetic mode either, so an
+		// integer beyond int64 they return is brought into it here.
+		if n, ok := res.(*object.BigInt); ok {
+			if res, ok = integerInMode(n.Value, mode); !ok {
+				res = newErrorf(object.OverflowError, "integer overflow: result of %s", fn.Name)
+			}
+		}
+
+		// Builtins know nothing of the script, so their errors are placed
+		// at the call.
+		return withFrame(withPos(res, pos), object.Frame{Function: fn.Name, Builtin: true, Pos: pos})
+	}
+
+	return newErrorf(object.TypeError, "not a function: %s", function.Type())
+}
+
+// callBuiltin calls fn, converting a Go panic within it into an error.
+func callBuiltin(fn *object.Builtin, args []object.Object, kwargs object.Keywords) (res object.Object) {
+	defer func() {
+		if r := recover(); r != nil {
+			res = newErrorf(object.InternalError, "%v", r)
+		} else if res == nil {
+			// A host builtin returning nil is treated as returning null, so
+			// no Go nil reaches scripts.
+			res = Null
+		}
+	}()
+
+	switch {
+	case fn.KwFn != nil && (len(kwargs) > 0 || fn.Fn == nil):
+		return fn.KwFn(kwargs, args...)
+	case len(kwargs) > 0:
+		return newErrorf(object.ArityError, "builtin function does not accept keyword arguments, got %s", kwargs[0].Name)
+	default:
 		return fn.Fn(args...)
 	}
+}
 
-	return newError("not a function: " + string(function.Type()))
+// withFrame adds frame to obj's stack trace if it is an error.
+func withFrame(obj object.Object, frame object.Frame) object.Object {
+	if err, ok := obj.(*object.Error); ok {
+		err.Trace = append(err.Trace, frame)
+	}
+	return obj
 }
 
-func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
+func describeFunction(fn *object.Function) string {
+	if fn.Name == "" {
+		return "anonymous function"
+	}
+	return "`" + fn.Name + "`"
+}
+
+// extendFunctionEnv binds args and kwargs to fn's parameters in a new scope
+// enclosed by the function's closure. Missing arguments take their parameter's
+// default, which is evaluated in that scope so it may refer to other
+// parameters, and surplus positional arguments are collected into the rest
+// parameter.
+func extendFunctionEnv(fn *object.Function, args []object.Object, kwargs object.Keywords) (*object.Environment, *object.Error) {
+	required := len(fn.Parameters) - len(fn.Defaults)
+	given := len(args) + len(kwargs)
+	if given < required || (fn.Rest == nil && given > len(fn.Parameters)) {
+		var want string
+		switch {
+		case fn.Rest != nil:
+			want = fmt.Sprintf(">=%d", required)
+		case required != len(fn.Parameters):
+			want = fmt.Sprintf("=%d..%d", required, len(fn.Parameters))
+		default:
+			want = fmt.Sprintf("=%d", required)
+		}
+		return nil, newErrorf(object.ArityError, "wrong number of arguments to %s. got=%d, want%s", describeFunction(fn), given, want)
+	}
+
+	bound := make([]object.Object, len(fn.Parameters))
+	copy(bound, args)
+
+	for _, kw := range kwargs {
+		idx := slices.IndexFunc(fn.Parameters, func(param *ast.Identifier) bool {
+			return param.Value == kw.Name
+		})
+		if idx < 0 {
+			return nil, newErrorf(object.ArityError, "unknown keyword argument %s to %s", kw.Name, describeFunction(fn))
+		}
+		if bound[idx] != nil {
+			return nil, newErrorf(object.ArityError, "duplicate argument %s to %s", kw.Name, describeFunction(fn))
+		}
+		bound[idx] = kw.Value
+	}
+
 	env := object.NewEnvironment(fn.Env)
 	for i, param := range fn.Parameters {
-		env.Set(param.Value, args[i])
+		if bound[i] != nil {
+			env.Set(param.Value, bound[i])
+		}
+	}
+
+	for i, param := range fn.Parameters {
+		if bound[i] != nil {
+			continue
+		}
+
+		defExp, ok := fn.Defaults[param.Value]
+		if !ok {
+			return nil, newErrorf(object.ArityError, "missing argument %s to %s", param.Value, describeFunction(fn))
+		}
+
+		def := Eval(defExp, env)
+		if isError(def) {
+			return nil, def.(*object.Error)
+		}
+		env.Set(param.Value, def)
+	}
+
+	if fn.Rest != nil {
+		rest := []object.Object{}
+		if len(args) > len(fn.Parameters) {
+			rest = append(rest, args[len(fn.Parameters):]...)
+		}
+		env.Set(fn.Rest.Value, &object.Array{Elements: rest})
 	}
 
-	return env
+	return env, nil
 }
 
 func unwrapReturnValue(obj object.Object) object.Object {
@@ -159,9 +406,75 @@ func evalBoolean(b bool) object.Object {
 	return False
 }
 
-func evalProgram(stmts []ast.Statement, env *object.Environment) object.Object {
-	var result object.Object
-	for _, statement := range stmts {
+// declare binds ident in env, failing if that would redeclare a constant.
+func declare(env *object.Environment, ident *ast.Identifier, val object.Object, constant bool) *object.Error {
+	if err := env.Declare(ident.Value, val, constant); err != nil {
+		return envError(ident.Token.Pos, err, ident.Value)
+	}
+	return nil
+}
+
+// hoistFunctions binds every function declared directly within stmts before
+// any of them run, so declarations may be called before they appear and may
+// be mutually recursive.
+func hoistFunctions(stmts []ast.Statement, env *object.Environment) *object.Error {
+	for _, stmt := range stmts {
+		if decl, ok := stmt.(*ast.FunctionStatement); ok {
+			if err := declare(env, decl.Name, Eval(decl.Function, env), false); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// recoverPanic turns a Go panic raised while evaluating stmt into an error at
+// stmt, stored in result, so that a bug in the evaluator fails the script
+// rather than crashing its host. It must be deferred.
+func recoverPanic(result *object.Object, stmt *ast.Statement) {
+	if r := recover(); r != nil {
+		*result = newErrorfAt(statementPos(*stmt), object.InternalError, "%v", r)
+	}
+}
+
+// statementPos returns the position of the first token of stmt.
+func statementPos(stmt ast.Statement) token.Position {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		return stmt.Token.Pos
+	case *ast.ReturnStatement:
+		return stmt.Token.Pos
+	case *ast.ExpressionStatement:
+		return stmt.Token.Pos
+	case *ast.BlockStatement:
+		return stmt.Token.Pos
+	case *ast.FunctionStatement:
+		return stmt.Token.Pos
+	case *ast.WhileStatement:
+		return stmt.Token.Pos
+	case *ast.ForStatement:
+		return stmt.Token.Pos
+	case *ast.BreakStatement:
+		return stmt.Token.Pos
+	case *ast.ContinueStatement:
+		return stmt.Token.Pos
+	case *ast.ThrowStatement:
+		return stmt.Token.Pos
+	default:
+		return token.Position{}
+	}
+}
+
+func evalProgram(stmts []ast.Statement, env *object.Environment) (result object.Object) {
+	if err := hoistFunctions(stmts, env); err != nil {
+		return err
+	}
+
+	var statement ast.Statement
+	defer recoverPanic(&result, &statement)
+
+	result = Null
+	for _, statement = range stmts {
 		result = Eval(statement, env)
 
 		switch result := result.(type) {
@@ -169,37 +482,49 @@ func evalProgram(stmts []ast.Statement, env *object.Environment) object.Object {
 			return result.Value
 		case *object.Error:
 			return result
+		case *object.Break, *object.Continue:
+			return newErrorf(object.RuntimeError, "%s outside of loop", result.Inspect())
 		}
 	}
 
 	return result
 }
 
-func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
-	var result object.Object
+// evalBlockStatement runs block in a scope of its own, so its declarations
+// do not outlive it. An empty block evaluates to null.
+//
+// Go panics are recovered at the innermost block, so they become errors which
+// a try expression can catch and which gain a stack trace as they unwind.
+func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) (result object.Object) {
+	env = object.NewEnvironment(env)
+	if err := hoistFunctions(block.Statements, env); err != nil {
+		return err
+	}
 
-	for _, statement := range block.Statements {
+	var statement ast.Statement
+	defer recoverPanic(&result, &statement)
+
+	result = Null
+	for _, statement = range block.Statements {
 		result = Eval(statement, env)
 
-		if result != nil {
-			rt := result.Type()
-			if rt == object.ReturnValueType || rt == object.ErrorType {
-				return result
-			}
+		rt := result.Type()
+		if rt == object.ReturnValueType || rt == object.ErrorType || isLoopControl(result) {
+			return result
 		}
 	}
 
 	return result
 }
 
-func evalPrefixExpression(operator string, right object.Object) object.Object {
+func evalPrefixExpression(operator string, right object.Object, mode object.ArithmeticMode) object.Object {
 	switch operator {
 	case "!":
 		return evalExclaimOperatorExpression(right)
 	case "-":
-		return evalMinusPrefixOperatorExpression(right)
+		return evalMinusPrefixOperatorExpression(right, mode)
 	default:
-		return newError("unknown operator: " + operator + string(right.Type()))
+		return newErrorf(object.TypeError, "unknown operator: %s%s", operator, right.Type())
 	}
 }
 
@@ -216,44 +541,54 @@ func evalExclaimOperatorExpression(right object.Object) object.Object {
 	}
 }
 
-func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
-	if right.Type() != object.IntegerType {
-		return newError("unknown operator: -" + string(right.Type()))
+func evalMinusPrefixOperatorExpression(right object.Object, mode object.ArithmeticMode) object.Object {
+	switch right := right.(type) {
+	case *object.Integer:
+		return evalIntegerNegation(right.Value, mode)
+	case *object.BigInt:
+		return object.NewInteger(new(big.Int).Neg(right.Value))
+	case *object.Rational:
+		return object.NewRational(new(big.Rat).Neg(right.Value))
+	default:
+		return newErrorf(object.TypeError, "unknown operator: -%s", right.Type())
 	}
-
-	value := right.(*object.Integer).Value
-	return &object.Integer{Value: -value}
 }
 
-func evalInfixExpression(operator string, left, right object.Object) object.Object {
+func evalInfixExpression(operator string, left, right object.Object, mode object.ArithmeticMode) object.Object {
 	switch {
+	case isNumber(left) && isNumber(right) &&
+		(operator == "//" || left.Type() == object.RationalType || right.Type() == object.RationalType):
+		return evalRationalInfixExpression(operator, left, right, mode)
 	case left.Type() == object.IntegerType && right.Type() == object.IntegerType:
-		return evalIntegerInfixExpression(operator, left, right)
-	case left.Type() == object.StringType && right.Type() == object.StringType:
-		return evalStringInfixExpression(operator, left, right)
+		return evalIntegerInfixExpression(operator, left, right, mode)
+	case isInteger(left) && isInteger(right):
+		return evalBigIntInfixExpression(operator, left, right)
 	case operator == "==":
-		return evalBoolean(left == right)
+		return evalBoolean(object.Equal(left, right))
 	case operator == "!=":
-		return evalBoolean(left != right)
+		return evalBoolean(!object.Equal(left, right))
 	case left.Type() != right.Type():
-		return newErrorf("type mismatch: %s %s %s", left.Type(), operator, right.Type())
+		return newErrorf(object.TypeError, "type mismatch: %s %s %s", left.Type(), operator, right.Type())
+	case left.Type() == object.StringType:
+		return evalStringInfixExpression(operator, left, right)
+	case operator == "<" || operator == ">":
+		return evalOrderingExpression(operator, left, right)
 	default:
-		return newErrorf("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return newErrorf(object.TypeError, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
-func evalIntegerInfixExpression(operator string, lObj, rObj object.Object) object.Object {
+func evalIntegerInfixExpression(operator string, lObj, rObj object.Object, mode object.ArithmeticMode) object.Object {
 	left := lObj.(*object.Integer).Value
 	right := rObj.(*object.Integer).Value
 	switch operator {
-	case "+":
-		return &object.Integer{Value: left + right}
-	case "-":
-		return &object.Integer{Value: left - right}
-	case "*":
-		return &object.Integer{Value: left * right}
+	case "+", "-", "*":
+		return evalIntegerArithmetic(operator, left, right, mode)
 	case "/":
-		return &object.Integer{Value: left / right}
+		if right == 0 {
+			return newErrorf(object.DivisionByZero, "division by zero: %d / 0", left)
+		}
+		return evalIntegerArithmetic(operator, left, right, mode)
 	case "<":
 		return evalBoolean(left < right)
 	case ">":
@@ -263,16 +598,33 @@ func evalIntegerInfixExpression(operator string, lObj, rObj object.Object) objec
 	case "!=":
 		return evalBoolean(left != right)
 	default:
-		return newErrorf("unknown operator: %s %s %s", lObj.Type(), operator, rObj.Type())
+		return newErrorf(object.TypeError, "unknown operator: %s %s %s", lObj.Type(), operator, rObj.Type())
 	}
 }
 
 func evalStringInfixExpression(operator string, l, r object.Object) object.Object {
-	if operator != "+" {
-		return newErrorf("unknown operator: %s %s %s", l.Type(), operator, r.Type())
+	switch operator {
+	case "+":
+		left, right := l.(*object.String).Value, r.(*object.String).Value
+		return &object.String{Value: left + right}
+	case "<", ">":
+		return evalOrderingExpression(operator, l, r)
+	default:
+		return newErrorf(object.TypeError, "unknown operator: %s %s %s", l.Type(), operator, r.Type())
+	}
+}
+
+// evalOrderingExpression evaluates `<` and `>` between two objects of the same
+// type, which must have a natural order.
+func evalOrderingExpression(operator string, left, right object.Object) object.Object {
+	if _, ok := left.(object.Comparer); !ok {
+		return newErrorf(object.TypeError, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+
+	if operator == "<" {
+		return evalBoolean(object.Compare(left, right) < 0)
 	}
-	left, right := l.(*object.String).Value, r.(*object.String).Value
-	return &object.String{Value: left + right}
+	return evalBoolean(object.Compare(left, right) > 0)
 }
 
 func evalIfExpression(exp *ast.IfExpression, env *object.Environment) object.Object {
@@ -290,26 +642,149 @@ func evalIfExpression(exp *ast.IfExpression, env *object.Environment) object.Obj
 	}
 }
 
+// evalThrow raises val as an error. A caught error is raised again as it was,
+// keeping its kind and position; any other value becomes a UserError.
+func evalThrow(val object.Object, pos token.Position) object.Object {
+	if caught, ok := val.(*object.ErrorValue); ok {
+		return caught.Err
+	}
+
+	msg := val.Inspect()
+	if str, ok := val.(*object.String); ok {
+		msg = str.Value
+	}
+	return &object.Error{Message: msg, Kind: object.UserError, Pos: pos, Value: val}
+}
+
+// evalTryExpression evaluates the try block, handing any error it raises to
+// the catch block. The finally block runs last whatever happened, and only
+// replaces the result if it raises an error or leaves with return, break or
+// continue itself.
+func evalTryExpression(te *ast.TryExpression, env *object.Environment) object.Object {
+	result := Eval(te.Block, env)
+
+	if err, ok := result.(*object.Error); ok && te.Catch != nil {
+		catchEnv := object.NewEnvironment(env)
+		if te.Param != nil {
+			catchEnv.Set(te.Param.Value, &object.ErrorValue{Err: err})
+		}
+		result = Eval(te.Catch, catchEnv)
+	}
+
+	if te.Finally != nil {
+		fin := Eval(te.Finally, env)
+		if _, ok := fin.(*object.ReturnValue); ok || isError(fin) || isLoopControl(fin) {
+			return fin
+		}
+	}
+
+	return result
+}
+
+func evalWhileStatement(ws *ast.WhileStatement, env *object.Environment) object.Object {
+	for {
+		condition := Eval(ws.Condition, env)
+		if isError(condition) {
+			return condition
+		}
+		if !truthy(condition) {
+			return Null
+		}
+
+		result := Eval(ws.Body, env)
+		switch result.(type) {
+		case *object.Break:
+			return Null
+		case *object.ReturnValue, *object.Error:
+			return result
+		}
+	}
+}
+
+func evalRangeExpression(re *ast.RangeExpression, env *object.Environment) object.Object {
+	start := Eval(re.Start, env)
+	if isError(start) {
+		return start
+	}
+
+	end := Eval(re.End, env)
+	if isError(end) {
+		return end
+	}
+
+	for _, bound := range []object.Object{start, end} {
+		if bound.Type() == object.BigIntType {
+			return newErrorf(object.ValueError, "range bounds must fit in int64, got %s", bound.Inspect())
+		}
+	}
+	s, ok1 := start.(*object.Integer)
+	e, ok2 := end.(*object.Integer)
+	if !ok1 || !ok2 {
+		return newErrorf(object.TypeError, "range bounds must be INTEGER, got %s%s%s", start.Type(), re.Token.Literal, end.Type())
+	}
+
+	rng := &object.Range{Start: s.Value, End: e.Value, Step: 1, Exclusive: re.Token.Type == token.DOTDOTLT}
+	if re.Step != nil {
+		step := Eval(re.Step, env)
+		if isError(step) {
+			return step
+		}
+
+		if step.Type() == object.BigIntType {
+			return newErrorf(object.ValueError, "range step must fit in int64, got %s", step.Inspect())
+		}
+		st, ok := step.(*object.Integer)
+		if !ok {
+			return newErrorf(object.TypeError, "range step must be INTEGER, got %s", step.Type())
+		}
+		if st.Value == 0 {
+			return newErrorf(object.ValueError, "range step must not be zero")
+		}
+		rng.Step = st.Value
+	}
+
+	return rng
+}
+
 func evalIndexExpression(left, index object.Object) object.Object {
 	switch {
 	case left.Type() == object.ArrayType && index.Type() == object.IntegerType:
 		return evalArrayIndexExpression(left, index)
+	case left.Type() == object.StringType && index.Type() == object.IntegerType:
+		return evalStringIndexExpression(left, index)
+	case (left.Type() == object.ArrayType || left.Type() == object.StringType) && index.Type() == object.RangeType:
+		return evalRangeIndexExpression(left, index)
+	case (left.Type() == object.ArrayType || left.Type() == object.StringType) && index.Type() == object.BigIntType:
+		return bigIndexError(index, left)
 	case left.Type() == object.HashType:
 		return evalHashIndexExpression(left, index)
 	default:
-		return newErrorf("index operator not supported: %s", left.Type())
+		return newErrorf(object.TypeError, "index operator not supported: %s", left.Type())
+	}
+}
+
+// bigIndexError reports a BigInt index into an array or string, which is out
+// of range however long the collection is.
+func bigIndexError(index, collection object.Object) *object.Error {
+	length := 0
+	switch collection := collection.(type) {
+	case *object.Array:
+		length = len(collection.Elements)
+	case *object.String:
+		length = utf8.RuneCountInString(collection.Value)
 	}
+	return newErrorf(object.IndexError, "index out of range: %s with length %d", index.Inspect(), length)
 }
 
 func evalHashIndexExpression(hash, index object.Object) object.Object {
 	hashObject := hash.(*object.Hash)
 
-	key, ok := index.(object.Hashable)
+	key, ok := object.KeyOf(index)
 	if !ok {
-		return newErrorf("unusable as hash key: %s", index.Type())
+		return newErrorf(object.TypeError, "unusable as hash key: %s", index.Type())
 	}
 
-	pair, ok := hashObject.Pairs[key.HashKey()]
+	pair, ok := hashObject.Get(key)
 	if !ok {
 		return Null
 	}
@@ -319,38 +794,245 @@ func evalHashIndexExpression(hash, index object.Object) object.Object {
 
 func evalArrayIndexExpression(left, index object.Object) object.Object {
 	arrObj := left.(*object.Array)
-	idx := index.(*object.Integer).Value
-	maximum := int64(len(arrObj.Elements) - 1)
-
-	if idx < 0 || maximum < idx {
+	idx, ok := normaliseIndex(index.(*object.Integer).Value, len(arrObj.Elements))
+	if !ok {
 		return Null
 	}
+
 	return arrObj.Elements[idx]
 }
 
+func evalStringIndexExpression(left, index object.Object) object.Object {
+	runes := []rune(left.(*object.String).Value)
+	idx, ok := normaliseIndex(index.(*object.Integer).Value, len(runes))
+	if !ok {
+		return Null
+	}
+
+	return &object.String{Value: string(runes[idx])}
+}
+
+// evalRangeIndexExpression picks out the elements (or runes) at each position
+// of the range, so `xs[0..<n step 2]` selects every other element.
+func evalRangeIndexExpression(left, index object.Object) object.Object {
+	rng := index.(*object.Range)
+
+	var length int
+	var runes []rune
+	switch left := left.(type) {
+	case *object.Array:
+		length = len(left.Elements)
+	case *object.String:
+		runes = []rune(left.Value)
+		length = len(runes)
+	}
+
+	positions := make([]int64, 0, min(rng.Len(), int64(length)))
+	for i := int64(0); i < rng.Len(); i++ {
+		idx, ok := normaliseIndex(rng.At(i), length)
+		if !ok {
+			return newErrorf(object.IndexError, "index out of range: %d with length %d", rng.At(i), length)
+		}
+		positions = append(positions, idx)
+	}
+
+	switch left := left.(type) {
+	case *object.Array:
+		elems := make([]object.Object, len(positions))
+		for i, idx := range positions {
+			elems[i] = left.Elements[idx]
+		}
+		return &object.Array{Elements: elems}
+	default:
+		picked := make([]rune, len(positions))
+		for i, idx := range positions {
+			picked[i] = runes[idx]
+		}
+		return &object.String{Value: string(picked)}
+	}
+}
+
+func evalSliceExpression(ie *ast.IndexExpression, left object.Object, env *object.Environment) object.Object {
+	var bounds [2]*int64
+	for i, exp := range []ast.Expression{ie.Index, ie.End} {
+		if exp == nil {
+			continue
+		}
+
+		obj := Eval(exp, env)
+		if isError(obj) {
+			return obj
+		}
+
+		switch obj := obj.(type) {
+		case *object.Integer:
+			bounds[i] = &obj.Value
+		case *object.BigInt:
+			// Bounds are clamped, so one beyond int64 acts as the
+			// furthest int64 in its direction.
+			bound := int64(math.MaxInt64)
+			if obj.Value.Sign() < 0 {
+				bound = math.MinInt64
+			}
+			bounds[i] = &bound
+		default:
+			return newErrorf(object.TypeError, "slice bounds must be INTEGER, got %s", obj.Type())
+		}
+	}
+
+	switch left := left.(type) {
+	case *object.Array:
+		start, end := sliceBounds(bounds[0], bounds[1], len(left.Elements))
+		elems := make([]object.Object, end-start)
+		copy(elems, left.Elements[start:end])
+		return &object.Array{Elements: elems}
+	case *object.String:
+		runes := []rune(left.Value)
+		start, end := sliceBounds(bounds[0], bounds[1], len(runes))
+		return &object.String{Value: string(runes[start:end])}
+	default:
+		return newErrorf(object.TypeError, "slice operator not supported: %s", left.Type())
+	}
+}
+
+// sliceBounds resolves optional, possibly negative, slice bounds against a
+// length. Out of range bounds are clamped rather than reported, so `xs[:10]`
+// on a shorter array yields the whole array.
+func sliceBounds(lo, hi *int64, length int) (int, int) {
+	clamp := func(i int64) int {
+		if i < 0 {
+			i += int64(length)
+		}
+		return int(max(0, min(i, int64(length))))
+	}
+
+	start, end := 0, length
+	if lo != nil {
+		start = clamp(*lo)
+	}
+	if hi != nil {
+		end = clamp(*hi)
+	}
+
+	return min(start, end), end
+}
+
+// normaliseIndex resolves a negative index against the end of a collection of
+// the given length, reporting whether the result lies within it.
+func normaliseIndex(idx int64, length int) (int64, bool) {
+	if idx < 0 {
+		idx += int64(length)
+	}
+
+	return idx, 0 <= idx && idx < int64(length)
+}
+
+// evalHashLiteral evaluates each key then its value, in the order written.
 func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
-	pairs := make(map[object.HashKey]object.HashPair)
+	hash := object.NewHash()
 
-	for keyNode, valueNode := range node.Pairs {
-		key := Eval(keyNode, env)
+	for _, pair := range node.Pairs {
+		key := Eval(pair.Key, env)
 		if isError(key) {
 			return key
 		}
 
-		hashKey, ok := key.(object.Hashable)
+		hashKey, ok := object.KeyOf(key)
 		if !ok {
-			return newErrorf("unusable as hash key: %s", key.Type())
+			return newErrorfAt(node.Token.Pos, object.TypeError, "unusable as hash key: %s", key.Type())
 		}
 
-		value := Eval(valueNode, env)
+		value := Eval(pair.Value, env)
 		if isError(value) {
 			return value
 		}
 
-		hashed := hashKey.HashKey()
-		pairs[hashed] = object.HashPair{Key: key, Value: value}
+		hash.Set(hashKey, value)
+	}
+	return hash
+}
+
+func evalAssignExpression(node *ast.AssignExpression, env *object.Environment) object.Object {
+	switch target := node.Target.(type) {
+	case *ast.Identifier:
+		value := Eval(node.Value, env)
+		if isError(value) {
+			return value
+		}
+
+		if err := env.Assign(target.Value, value); err != nil {
+			return envError(target.Token.Pos, err, target.Value)
+		}
+		return value
+	case *ast.IndexExpression:
+		left := Eval(target.Left, env)
+		if isError(left) {
+			return left
+		}
+
+		index := Eval(target.Index, env)
+		if isError(index) {
+			return index
+		}
+
+		value := Eval(node.Value, env)
+		if isError(value) {
+			return value
+		}
+
+		return withPos(evalIndexAssignment(left, index, value), target.Token.Pos)
+	case *ast.DotExpression:
+		left := Eval(target.Left, env)
+		if isError(left) {
+			return left
+		}
+		if _, ok := left.(*object.Hash); !ok {
+			return newErrorf(object.TypeError, "field assignment not supported: %s", left.Type())
+		}
+
+		value := Eval(node.Value, env)
+		if isError(value) {
+			return value
+		}
+
+		return withPos(evalIndexAssignment(left, &object.String{Value: target.Name.Value}, value), target.Token.Pos)
+	default:
+		return newErrorf(object.RuntimeError, "invalid assignment target: %s", node.Target)
+	}
+}
+
+func evalIndexAssignment(left, index, value object.Object) object.Object {
+	if err := checkMutable(left); err != nil {
+		return err
+	}
+
+	switch left := left.(type) {
+	case *object.Array:
+		if index.Type() == object.BigIntType {
+			return bigIndexError(index, left)
+		}
+		integer, ok := index.(*object.Integer)
+		if !ok {
+			return newErrorf(object.TypeError, "array index must be INTEGER, got %s", index.Type())
+		}
+		idx, ok := normaliseIndex(integer.Value, len(left.Elements))
+		if !ok {
+			return newErrorf(object.IndexError, "index out of range: %d with length %d", integer.Value, len(left.Elements))
+		}
+
+		left.Elements[idx] = value
+		return value
+	case *object.Hash:
+		key, ok := object.KeyOf(index)
+		if !ok {
+			return newErrorf(object.TypeError, "unusable as hash key: %s", index.Type())
+		}
+
+		left.Set(key, value)
+		return value
+	default:
+		return newErrorf(object.TypeError, "index assignment not supported: %s", left.Type())
 	}
-	return &object.Hash{Pairs: pairs}
 }
 
 func truthy(o object.Object) bool {
@@ -366,14 +1048,213 @@ func truthy(o object.Object) bool {
 	}
 }
 
-func newError(s string) *object.Error {
-	return newErrorf(s)
+func newErrorf(kind object.ErrorKind, s string, v ...any) *object.Error {
+	return &object.Error{Message: fmt.Sprintf(s, v...), Kind: kind}
 }
 
-func newErrorf(s string, v ...any) *object.Error {
-	return &object.Error{Message: fmt.Sprintf(s, v...)}
+func newErrorfAt(pos token.Position, kind object.ErrorKind, s string, v ...any) *object.Error {
+	return &object.Error{Message: fmt.Sprintf(s, v...), Kind: kind, Pos: pos}
+}
+
+// envError describes err, returned by env for name, as a positioned error.
+func envError(pos token.Position, err error, name string) *object.Error {
+	kind := object.ImmutableError
+	if errors.Is(err, object.ErrUndeclared) {
+		kind = object.NameError
+	}
+	return newErrorfAt(pos, kind, "%s: %s", err, name)
+}
+
+// withPos places obj at pos if it is an error without a position.
+func withPos(obj object.Object, pos token.Position) object.Object {
+	if err, ok := obj.(*object.Error); ok && !err.Pos.IsValid() {
+		err.Pos = pos
+	}
+	return obj
+}
+
+// checkMutable returns an error if obj has been frozen.
+func checkMutable(obj object.Object) *object.Error {
+	switch obj := obj.(type) {
+	case *object.Array:
+		if obj.Frozen {
+			return newErrorf(object.ImmutableError, "cannot modify frozen %s", obj.Type())
+		}
+	case *object.Hash:
+		if obj.Frozen {
+			return newErrorf(object.ImmutableError, "cannot modify frozen %s", obj.Type())
+		}
+	}
+	return nil
+}
+
+// freeze makes obj, and every array and hash within it, immutable.
+func freeze(obj object.Object) {
+	switch obj := obj.(type) {
+	case *object.Array:
+		if obj.Frozen {
+			return
+		}
+		obj.Frozen = true
+		for _, elem := range obj.Elements {
+			freeze(elem)
+		}
+	case *object.Hash:
+		if obj.Frozen {
+			return
+		}
+		obj.Frozen = true
+		for _, pair := range obj.Pairs() {
+			freeze(pair.Value)
+		}
+	}
 }
 
 func isError(o object.Object) bool {
 	return o != nil && o.Type() == object.ErrorType
 }
+
+func isLoopControl(o object.Object) bool {
+	return o == breakSignal || o == continueSignal
+}
+
+// evalPipeExpression evaluates `x |> f(a)` as `f(x, a)`, and `x |> f` as
+// `f(x)` when the right hand side is not a call.
+func evalPipeExpression(node *ast.PipeExpression, env *object.Environment) object.Object {
+	left := Eval(node.Left, env)
+	if isError(left) {
+		return left
+	}
+
+	call, ok := node.Right.(*ast.CallExpression)
+	if !ok {
+		fn := Eval(node.Right, env)
+		if isError(fn) {
+			return fn
+		}
+		return applyFunction(fn, []object.Object{left}, nil, node.Token.Pos, env.Arithmetic())
+	}
+
+	res, _ := evalCallExpression(call, []object.Object{left}, env)
+	return res
+}
+
+// evalChain evaluates one link of a chain of calls, field accesses and
+// indexes, such as `a?.b[0].c()`. It reports whether an optional link met
+// null, in which case the rest of the chain is skipped and evaluates to null
+// rather than failing on the null.
+func evalChain(node ast.Expression, env *object.Environment) (object.Object, bool) {
+	switch node := node.(type) {
+	case *ast.CallExpression:
+		return evalCallExpression(node, nil, env)
+	case *ast.DotExpression:
+		left, skip := evalChainLeft(node.Left, node.Optional, env)
+		if skip || isError(left) {
+			return left, skip
+		}
+		return withPos(evalFieldExpression(left, node.Name.Value), node.Token.Pos), false
+	case *ast.IndexExpression:
+		left, skip := evalChainLeft(node.Left, node.Optional, env)
+		if skip || isError(left) {
+			return left, skip
+		}
+		if node.Slice {
+			return withPos(evalSliceExpression(node, left, env), node.Token.Pos), false
+		}
+
+		index := Eval(node.Index, env)
+		if isError(index) {
+			return index, false
+		}
+		return withPos(evalIndexExpression(left, index), node.Token.Pos), false
+	default:
+		return Eval(node, env), false
+	}
+}
+
+// evalChainLeft evaluates the left side of a link in a chain, reporting
+// whether the chain stops here with null.
+func evalChainLeft(left ast.Expression, optional bool, env *object.Environment) (object.Object, bool) {
+	obj, skip := evalChain(left, env)
+	if skip || optional && obj == Null {
+		return Null, true
+	}
+	return obj, false
+}
+
+// evalCallExpression calls node's function with leading prepended to the
+// arguments written in the call. A call of the form `value.name(...)` is a
+// method call, see lookupMethod.
+func evalCallExpression(node *ast.CallExpression, leading []object.Object, env *object.Environment) (object.Object, bool) {
+	var fn object.Object
+	if dot, ok := node.Function.(*ast.DotExpression); ok {
+		receiver, skip := evalChainLeft(dot.Left, dot.Optional, env)
+		if skip || isError(receiver) {
+			return receiver, skip
+		}
+
+		var self object.Object
+		if fn, self = lookupMethod(receiver, dot.Name.Value); isError(fn) {
+			return fn, false
+		}
+		if self != nil {
+			leading = append([]object.Object{self}, leading...)
+		}
+	} else {
+		var skip bool
+		if fn, skip = evalChain(node.Function, env); skip || isError(fn) {
+			return fn, skip
+		}
+	}
+
+	args := evalExpressions(node.Arguments, env)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0], false
+	}
+	kwargs, err := evalKeywords(node.Keywords, env)
+	if err != nil {
+		return err, false
+	}
+
+	return applyFunction(fn, append(leading, args...), kwargs, node.Token.Pos, env.Arithmetic()), false
+}
+
+// evalFieldExpression evaluates `left.name`, which reads the string key name
+// from a hash.
+func evalFieldExpression(left object.Object, name string) object.Object {
+	if caught, ok := left.(*object.ErrorValue); ok {
+		return errorField(caught.Err, name)
+	}
+
+	hash, ok := left.(*object.Hash)
+	if !ok {
+		return newErrorf(object.TypeError, "field access not supported: %s", left.Type())
+	}
+
+	pair, ok := hash.Get(&object.String{Value: name})
+	if !ok {
+		return Null
+	}
+	return pair.Value
+}
+
+// errorField returns the field name of a caught error.
+func errorField(err *object.Error, name string) object.Object {
+	switch name {
+	case "message":
+		return &object.String{Value: err.Message}
+	case "kind":
+		return &object.String{Value: string(err.ErrorKind())}
+	case "line":
+		return &object.Integer{Value: int64(err.Pos.Line)}
+	case "column":
+		return &object.Integer{Value: int64(err.Pos.Column)}
+	case "value":
+		if err.Value == nil {
+			return Null
+		}
+		return err.Value
+	default:
+		return newErrorf(object.TypeError, "unknown field %s for %s", name, object.ErrorValueType)
+	}
+}
@@ -1,12 +1,17 @@
 package evaluator_test
 
 import (
+	"errors"
+	"fmt"
+	"math"
 	"testing"
 
+	"git.tigh.dev/tigh-latte/monkeyscript/ast"
 	"git.tigh.dev/tigh-latte/monkeyscript/evaluator"
 	"git.tigh.dev/tigh-latte/monkeyscript/lexer"
 	"git.tigh.dev/tigh-latte/monkeyscript/object"
 	"git.tigh.dev/tigh-latte/monkeyscript/parser"
+	"git.tigh.dev/tigh-latte/monkeyscript/token"
 )
 
 func TestEvalIntegerExpression(t *testing.T) {
@@ -58,6 +63,12 @@ func TestEvalIntegerExpression(t *testing.T) {
 	}, {
 		input:    "(5 + 10 * 2 + 15 / 3) * 2 + -10",
 		expected: 50,
+	}, {
+		input:    "0xFF + 0b1010 + 0o17 + 1_000",
+		expected: 1280,
+	}, {
+		input:    "017",
+		expected: 17,
 	}}
 
 	for _, test := range tests {
@@ -272,6 +283,12 @@ func TestErrorHandling(t *testing.T) {
 	}, {
 		input:    `{"name": "Monkey"}[fn(x) { x }];`,
 		expected: "unusable as hash key: FUNCTION",
+	}, {
+		input:    "let d = 0; 10 / d",
+		expected: "division by zero: 10 / 0",
+	}, {
+		input:    "fn avg(xs) { 0 / len(xs) }; avg([])",
+		expected: "division by zero: 0 / 0",
 	}}
 
 	for _, test := range tests {
@@ -312,6 +329,175 @@ func TestLetStatements(t *testing.T) {
 	}
 }
 
+func TestDestructuringLet(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{{
+		input:    "let [a, b] = [1, 2]; a * 10 + b",
+		expected: 12,
+	}, {
+		input:    "let [head, ...tail] = [1, 2, 3]; tail",
+		expected: inspected("[2, 3]"),
+	}, {
+		input:    "let [head, ...tail] = [1]; tail",
+		expected: inspected("[]"),
+	}, {
+		input:    "let [a, b = 5] = [1]; b",
+		expected: 5,
+	}, {
+		input:    "let [a, b = a + 1] = [1]; b",
+		expected: 2,
+	}, {
+		input:    `let {amount, currency} = {"amount": 10, "currency": "GBP"}; currency`,
+		expected: "GBP",
+	}, {
+		input:    `let {amount: amt} = {"amount": 10}; amt`,
+		expected: 10,
+	}, {
+		input:    `let {amount, currency = "USD"} = {"amount": 10}; currency`,
+		expected: "USD",
+	}, {
+		input:    `let {meta: {id}, tags: [first]} = {"meta": {"id": 7}, "tags": ["a", "b"]}; id`,
+		expected: errorMessage("array pattern [first] expects 1 elements, got 2"),
+	}, {
+		input:    `let {meta: {id}, tags: [first, ...rest]} = {"meta": {"id": 7}, "tags": ["a", "b"]}; [id, first, rest]`,
+		expected: inspected(`[7, a, [b]]`),
+	}, {
+		input:    `let {id, ...others} = {"id": 1, "x": 2}; others`,
+		expected: inspected("{x:2}"),
+	}, {
+		input:    `let [{a}, {a: b}] = [{"a": 1}, {"a": 2}]; a + b`,
+		expected: 3,
+	}, {
+		input:    "let [a, b] = [1];",
+		expected: errorMessage("array pattern [a, b] expects at least 2 elements, got 1"),
+	}, {
+		input:    "let [a] = 5;",
+		expected: errorMessage("cannot destructure INTEGER with array pattern [a]"),
+	}, {
+		input:    `let {a} = [1];`,
+		expected: errorMessage("cannot destructure ARRAY with hash pattern {a}"),
+	}, {
+		input:    `let {amount, currency} = {"amount": 1};`,
+		expected: errorMessage(`missing key "currency" for hash pattern {amount, currency}`),
+	}}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+		testExpectedObject(t, evaluated, test.expected)
+	}
+}
+
+func TestDestructuringErrorPosition(t *testing.T) {
+	input := "let x = 1;\nlet {a,\n  b} = {\"a\": 1};"
+
+	errObj, ok := testEval(input).(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned")
+	}
+
+	if errObj.Pos.String() != "3:3" {
+		t.Errorf("wrong error position. want=%q, got=%q", "3:3", errObj.Pos)
+	}
+	if errObj.Inspect() != `ERROR: 3:3: missing key "b" for hash pattern {a, b}` {
+		t.Errorf("wrong Inspect(). got=%q", errObj.Inspect())
+	}
+}
+
+func TestLambdasAndPipelines(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{"let double = |x| x * 2; double(4)", 8},
+		{"let add = |a, b| a + b; add(2, 3)", 5},
+		{"let inc = x => x + 1; inc(1)", 2},
+		{"let f = || { let a = 2; a * 3 }; f()", 6},
+		{"let f = |a, b = 10| a + b; f(1)", 11},
+		{"let n = 5; let addN = |x| x + n; addN(1)", 6},
+		{"4 |> |x| x * 2", 8},
+		{"let sub = |a, b| a - b; 10 |> sub(3)", 7},
+		{"let sub = |a, b| a - b; 10 |> sub(3) |> sub(2)", 5},
+		{"[1, 2, 3] |> len", 3},
+		{"[1, 2] |> append!(3) |> len() == 3", true},
+		{"let f = |a, ...rest| len(rest); 1 |> f(2, 3)", 2},
+		{`let f = |a, b = 1| a + b; 1 |> f(b: 5)`, 6},
+		{"let sub = |a, b| a - b; 10 |> sub", errorMessage("wrong number of arguments to `sub`. got=1, want=2")},
+		{"1 |> 2", errorMessage("not a function: INTEGER")},
+		{"x |> len", errorMessage("identifier not found: x")},
+		{"1 |> f", errorMessage("identifier not found: f")},
+	}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+		testExpectedObject(t, evaluated, test.expected)
+	}
+}
+
+func TestMatchExpressions(t *testing.T) {
+	classify := `let classify = fn(x) {
+		match (x) {
+			0 => "zero",
+			-1 => "minus one",
+			1..<10 => "small",
+			n: integer if n < 0 => "negative",
+			n: integer => "large",
+			"GBP" => "sterling",
+			s: string => "string " + s,
+			true => "yes",
+			[] => "empty",
+			[only] => "one",
+			[0, ...rest] => "zero then " + classify(len(rest)),
+			[a, b] => "pair",
+			{kind: "refund", amount} => "refund of " + classify(amount),
+			{amount} if amount > 100 => "big",
+			{} => "hash",
+			_ => "other"
+		}
+	};`
+
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{"classify(0)", "zero"},
+		{"classify(-1)", "minus one"},
+		{"classify(5)", "small"},
+		{"classify(-5)", "negative"},
+		{"classify(50)", "large"},
+		{`classify("GBP")`, "sterling"},
+		{`classify("EUR")`, "string EUR"},
+		{"classify(true)", "yes"},
+		{"classify(false)", "other"},
+		{"classify([])", "empty"},
+		{"classify([1])", "one"},
+		{"classify([0, 1, 2])", "zero then small"},
+		{"classify([1, 2])", "pair"},
+		{"classify([1, 2, 3])", "other"},
+		{`classify({"kind": "refund", "amount": 3})`, "refund of small"},
+		{`classify({"amount": 300})`, "big"},
+		{`classify({"amount": 3})`, "hash"},
+		{"classify(fn() {})", "other"},
+		{"match (1) { 2 => 3 }", nil},
+		{"match (5) { 0..10 step 5 => 1, _ => 2 }", 1},
+		{"match (4) { 0..10 step 5 => 1, _ => 2 }", 2},
+		{"match (10) { 0..<10 => 1, _ => 2 }", 2},
+		{"let x = 1; match (2) { x => x }", 2},
+		{"let x = 1; match (2) { x => x }; x", 1},
+		{"match ([1, 2]) { [a, 3] => a, [b, c] => a }", errorMessage("identifier not found: a")},
+		{"let f = fn(x) { match (x) { 1 => { return 10; }, _ => 0 }; 20 }; f(1)", 10},
+		{"match (1) { n: money => n }", errorMessage("unknown type in pattern: money")},
+		{"match (y) { _ => 1 }", errorMessage("identifier not found: y")},
+		{"match (1) { n if y => 1 }", errorMessage("identifier not found: y")},
+	}
+
+	for _, test := range tests {
+		evaluated := testEval(classify + test.input)
+		testExpectedObject(t, evaluated, test.expected)
+	}
+}
+
 func TestFunctionObject(t *testing.T) {
 	input := "fn(x) { x + 2; };"
 
@@ -363,216 +549,1330 @@ func TestFunctionApplciation(t *testing.T) {
 	}
 }
 
-func TestClosures(t *testing.T) {
-	input := `
-	let newAdder = fn(x) {
-		fn(y) { x + y };
-	};
-
-	let addTwo = newAdder(2);
-	addTwo(2);
-	`
-
-	testIntegerObject(t, testEval(input), 4)
-}
-
-func TestStringLiteral(t *testing.T) {
-	input := `"Hello World!"`
-
-	evaluated := testEval(input)
-	str, ok := evaluated.(*object.String)
-	if !ok {
-		t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
-	}
-
-	if str.Value != "Hello World!" {
-		t.Errorf("String has wrong value. got=%q", str.Value)
-	}
-}
-
-func TestStringConcatentation(t *testing.T) {
-	input := `"Hello" + " " + "World!"`
-
-	evaluated := testEval(input)
-	str, ok := evaluated.(*object.String)
-	if !ok {
-		t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
-	}
-
-	if str.Value != "Hello World!" {
-		t.Errorf("String has wrong value. got=%q", str.Value)
-	}
-}
-
-func TestBuiltinFunctions(t *testing.T) {
+func TestFunctionDeclarations(t *testing.T) {
 	tests := []struct {
 		input    string
 		expected any
 	}{{
-		input:    `len("")`,
-		expected: 0,
+		input:    "fn add(x, y) { x + y }; add(2, 3)",
+		expected: 5,
 	}, {
-		input:    `len("four")`,
-		expected: 4,
+		input:    "let r = double(4); fn double(x) { x * 2 }; r",
+		expected: 8,
 	}, {
-		input:    `len("hello world")`,
-		expected: 11,
+		input: `
+		fn isEven(n) { if (n == 0) { return true; } isOdd(n - 1) }
+		fn isOdd(n) { if (n == 0) { return false; } isEven(n - 1) }
+		if (isEven(10)) { 1 } else { 0 }
+		`,
+		expected: 1,
 	}, {
-		input:    `len(1)`,
-		expected: "argument to `len` not supported, got INTEGER",
+		input:    "fn fact(n) { if (n < 2) { return 1; } n * fact(n - 1) }; fact(5)",
+		expected: 120,
 	}, {
-		input:    `len("one", "two")`,
-		expected: "wrong number of arguments. got=2, want=1",
+		input:    "fn outer() { let r = inner(); fn inner() { 7 }; r }; outer()",
+		expected: 7,
+	}, {
+		input:    "fn outer() { fn inner() { 7 }; 0 }; outer(); inner()",
+		expected: errorMessage("identifier not found: inner"),
+	}, {
+		input:    "fn add(x, y) { x + y }; add(1)",
+		expected: errorMessage("wrong number of arguments to `add`. got=1, want=2"),
+	}, {
+		input:    "let add = fn(x, y) { x + y }; add(1, 2, 3)",
+		expected: errorMessage("wrong number of arguments to `add`. got=3, want=2"),
+	}, {
+		input:    "fn(x) { x }()",
+		expected: errorMessage("wrong number of arguments to anonymous function. got=0, want=1"),
+	}, {
+		input:    "fn add(x, y) { x + y }",
+		expected: inspected("fn add(x, y) {\n(x + y)\n}"),
+	}, {
+		input:    "let sub = fn(x, y) { x - y }; sub",
+		expected: inspected("fn sub(x, y) {\n(x - y)\n}"),
+	}, {
+		input:    "fn(x) { x }",
+		expected: inspected("fn (x) {\nx\n}"),
 	}}
 
 	for _, test := range tests {
 		evaluated := testEval(test.input)
-		switch expected := test.expected.(type) {
-		case int:
-			testIntegerObject(t, evaluated, int64(expected))
-		case string:
-			errObj, ok := evaluated.(*object.Error)
-			if !ok {
-				t.Errorf("object is not Error. got=%T (%+v)", errObj, errObj)
-				continue
-			}
-			if errObj.Message != expected {
-				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
-			}
-		}
-	}
-}
-
-func TestArrayLiterals(t *testing.T) {
-	input := "[1, 2* 2, 3 + 3]"
-
-	evaluated := testEval(input)
-	result, ok := evaluated.(*object.Array)
-	if !ok {
-		t.Fatalf("object is not Array. got=%T (%+v)", evaluated, evaluated)
-	}
-
-	if len(result.Elements) != 3 {
-		t.Fatalf("array has wrong num of elements. got=%d", len(result.Elements))
+		testExpectedObject(t, evaluated, test.expected)
 	}
-
-	testIntegerObject(t, result.Elements[0], 1)
-	testIntegerObject(t, result.Elements[1], 4)
-	testIntegerObject(t, result.Elements[2], 6)
 }
 
-func TestArrayIndexExpressions(t *testing.T) {
+func TestDefaultAndVariadicParameters(t *testing.T) {
 	tests := []struct {
 		input    string
 		expected any
 	}{{
-		input:    "[1, 2, 3][0]",
-		expected: 1,
-	}, {
-		input:    "[1, 2, 3][1]",
-		expected: 2,
+		input:    "let f = fn(a, b = 10) { a + b }; f(1)",
+		expected: 11,
 	}, {
-		input:    "[1, 2, 3][2]",
+		input:    "let f = fn(a, b = 10) { a + b }; f(1, 2)",
 		expected: 3,
 	}, {
-		input:    "let i = 0; [1][i]",
-		expected: 1,
+		input:    "let f = fn(a, b = a * 2) { a + b }; f(3)",
+		expected: 9,
 	}, {
-		input:    "[1, 2, 3][1 + 1]",
-		expected: 3,
+		input:    `fn round(amount, rounding = "half_even") { rounding }; round(1)`,
+		expected: "half_even",
 	}, {
-		input:    "let myArray = [1, 2, 3]; myArray[2]",
+		input:    "let f = fn(...rest) { len(rest) }; f()",
+		expected: 0,
+	}, {
+		input:    "let f = fn(a, ...rest) { len(rest) }; f(1, 2, 3)",
+		expected: 2,
+	}, {
+		input:    "let sum = fn(...xs) { let t = 0; for (x in xs) { t = t + x }; t }; sum(1, 2, 3, 4)",
+		expected: 10,
+	}, {
+		input:    "let f = fn(a, b = 2, ...rest) { a + b + len(rest) }; f(1)",
 		expected: 3,
 	}, {
-		input:    "let myArray = [1, 2, 3]; myArray[0] + myArray[1] + myArray[2];",
-		expected: 6,
+		input:    "let f = fn(a, b = 2) { a + b }; f()",
+		expected: errorMessage("wrong number of arguments to `f`. got=0, want=1..2"),
 	}, {
-		input:    "let myArray = [1, 2, 3]; let i = myArray[0]; myArray[i]",
-		expected: 2,
+		input:    "let f = fn(a, b = 2) { a + b }; f(1, 2, 3)",
+		expected: errorMessage("wrong number of arguments to `f`. got=3, want=1..2"),
 	}, {
-		input:    "[1, 2, 3][3]",
-		expected: nil,
+		input:    "let f = fn(a, ...rest) { a }; f()",
+		expected: errorMessage("wrong number of arguments to `f`. got=0, want>=1"),
 	}, {
-		input:    "[1, 2, 3][-1]",
-		expected: nil,
+		input:    "let f = fn(a, b = c) { a }; f(1)",
+		expected: errorMessage("identifier not found: c"),
+	}, {
+		input:    "fn(a = 1, ...rest) {}",
+		expected: inspected("fn (a = 1, ...rest) {\n\n}"),
 	}}
 
 	for _, test := range tests {
 		evaluated := testEval(test.input)
-		integer, ok := test.expected.(int)
-		if ok {
-			testIntegerObject(t, evaluated, int64(integer))
-		} else {
-			testNullObject(t, evaluated)
-		}
+		testExpectedObject(t, evaluated, test.expected)
 	}
 }
 
-func TestHashLiterals(t *testing.T) {
-	input := `let two = "two";
-	{
-		"one": 10 - 9,
-		two: 1 + 1,
-		"thr" + "ee": 6 / 2,
-		4: 4,
-		true: 5,
-		false: 6
-	}`
-
-	evaluated := testEval(input)
-	result, ok := evaluated.(*object.Hash)
-	if !ok {
-		t.Fatalf("Eval didn't return Hash. got=%T (%+v)", evaluated, evaluated)
-	}
-
-	expected := map[object.HashKey]int64{
-		(&object.String{Value: "one"}).HashKey():   1,
-		(&object.String{Value: "two"}).HashKey():   2,
-		(&object.String{Value: "three"}).HashKey(): 3,
-		(&object.Integer{Value: 4}).HashKey():      4,
-		evaluator.True.HashKey():                   5,
-		evaluator.False.HashKey():                  6,
-	}
-
-	if len(result.Pairs) != len(expected) {
-		t.Fatalf("Hash has wrong num of pairs. got=%d", len(result.Pairs))
-	}
-
-	for expectedKey, expectedValue := range expected {
-		pair, ok := result.Pairs[expectedKey]
-		if !ok {
-			t.Errorf("no pair for given key in Pairs")
-		}
+func TestSpreadExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{{
+		input:    "let add = fn(a, b, c) { a + b + c }; let args = [1, 2, 3]; add(...args)",
+		expected: 6,
+	}, {
+		input:    "let add = fn(a, b, c) { a + b + c }; add(1, ...[2, 3])",
+		expected: 6,
+	}, {
+		input:    "let a = [1, 2]; let b = [3]; [...a, ...b, 4]",
+		expected: inspected("[1, 2, 3, 4]"),
+	}, {
+		input:    "[0, ...1..3]",
+		expected: inspected("[0, 1, 2, 3]"),
+	}, {
+		input:    "let a = [1]; let b = [...a]; append!(b, 2); len(a)",
+		expected: 1,
+	}, {
+		input:    "let f = fn(...xs) { xs }; f(...[], ...[1])",
+		expected: inspected("[1]"),
+	}, {
+		input:    "len(...[[1, 2]])",
+		expected: 2,
+	}, {
+		input:    "[...1]",
+		expected: errorMessage("cannot spread INTEGER"),
+	}, {
+		input:    "[...x]",
+		expected: errorMessage("identifier not found: x"),
+	}, {
+		input:    "let a = ...[1]",
+		expected: errorMessage("spread is only allowed in calls and array literals: ...[1]"),
+	}}
 
-		testIntegerObject(t, pair.Value, expectedValue)
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+		testExpectedObject(t, evaluated, test.expected)
 	}
 }
 
-func TestHashIndexExpression(t *testing.T) {
+func TestKeywordArguments(t *testing.T) {
 	tests := []struct {
 		input    string
 		expected any
 	}{{
-		input:    `{"foo": 5}["foo"]`,
-		expected: 5,
+		input:    "let f = fn(a, b) { a - b }; f(b: 1, a: 10)",
+		expected: 9,
 	}, {
-		input:    `{"foo": 5}["bar"]`,
-		expected: nil,
+		input:    "let f = fn(a, b) { a - b }; f(10, b: 1)",
+		expected: 9,
 	}, {
-		input:    `let key = "foo"; {"foo": 5}[key]`,
-		expected: 5,
+		input:    "let f = fn(a, b = 2, c = 3) { a * 100 + b * 10 + c }; f(1, c: 9)",
+		expected: 129,
 	}, {
-		input:    `{}["foo"]`,
-		expected: nil,
+		input:    "let f = fn(a = b * 2, b = 1) { a + b }; f(b: 5)",
+		expected: 15,
 	}, {
-		input:    `{5: 5}[5]`,
-		expected: 5,
+		input:    "let f = fn(a, b) { a - b }; f(1, c: 2)",
+		expected: errorMessage("unknown keyword argument c to `f`"),
 	}, {
-		input:    `{true: 5}[true]`,
-		expected: 5,
+		input:    "let f = fn(a, b) { a - b }; f(1, a: 2)",
+		expected: errorMessage("duplicate argument a to `f`"),
 	}, {
-		input:    `{false: 5}[false]`,
-		expected: 5,
+		input:    "let f = fn(a, b, c = 1) { a }; f(1, c: 2)",
+		expected: errorMessage("missing argument b to `f`"),
+	}, {
+		input:    "let f = fn(a) { a }; f(1, a: 2)",
+		expected: errorMessage("wrong number of arguments to `f`. got=2, want=1"),
+	}, {
+		input:    "let f = fn(a, ...rest) { a }; f(1, rest: 2)",
+		expected: errorMessage("unknown keyword argument rest to `f`"),
+	}, {
+		input:    `len("abc", x: 1)`,
+		expected: errorMessage("builtin function does not accept keyword arguments, got x"),
+	}, {
+		input:    "let f = fn(a) { a }; f(a: x)",
+		expected: errorMessage("identifier not found: x"),
+	}}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+		testExpectedObject(t, evaluated, test.expected)
+	}
+}
+
+func TestBuiltinKeywordArguments(t *testing.T) {
+	env := object.NewEnvironment(nil)
+	env.Set("scale", &object.Builtin{
+		KwFn: func(kwargs object.Keywords, args ...object.Object) object.Object {
+			factor := int64(1)
+			if f, ok := kwargs.Get("by"); ok {
+				factor = f.(*object.Integer).Value
+			}
+			return &object.Integer{Value: args[0].(*object.Integer).Value * factor}
+		},
+	})
+
+	tests := []struct {
+		input    string
+		expected int64
+	}{{
+		input:    "scale(3)",
+		expected: 3,
+	}, {
+		input:    "scale(3, by: 4)",
+		expected: 12,
+	}}
+
+	for _, test := range tests {
+		program := parser.New(lexer.New(test.input)).ParseProgram()
+		testIntegerObject(t, evaluator.Eval(program, env), test.expected)
+	}
+}
+
+func TestDotAccessAndMethods(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`let txn = {"amount": 5}; txn.amount`, 5},
+		{`let txn = {"amount": 5}; txn.currency`, nil},
+		{`let txn = {"meta": {"id": 7}}; txn.meta.id`, 7},
+		{`let txn = {"amount": 5}; txn.amount = txn.amount * 2; txn["amount"]`, 10},
+		{`let txn = {}; txn.amount = 3`, 3},
+		{`[1, 2, 3].len()`, 3},
+		{`let rows = [1, 2]; rows.append!(3); rows.last()`, 3},
+		{`"abc".len()`, 3},
+		{`"GBP".lower()`, "gbp"},
+		{`" x ".trim().upper()`, "X"},
+		{`"a,b,c".split(",").join("-")`, "a-b-c"},
+		{`"abc".contains("bc")`, true},
+		{`{"a": 1, "b": 2}.len()`, 2},
+		{`{"a": 1}.has("a")`, true},
+		{`{"a": 1}.has("b")`, false},
+		{`(0..<5).len()`, 5},
+		{`let m = {"double": |x| x * 2}; m.double(4)`, 8},
+		{`let h = {"len": 10}; h.len`, 10},
+		{`let h = {"len": 10}; h.len()`, 1},
+		{`let h = {}; h.keys = 5; h.keys().len()`, 1},
+		{`let h = {"has": fn(k) { false }}; h.has("has")`, true},
+		{`[3, 1] |> first`, 3},
+		{`[1, 2] |> push(3) |> len()`, 3},
+		{`5.amount`, errorMessage("field access not supported: INTEGER")},
+		{`let x = 5; x.amount = 1`, errorMessage("field assignment not supported: INTEGER")},
+		{`[1].upper()`, errorMessage("unknown method upper for ARRAY")},
+		{`let h = {"n": 1}; h.n()`, errorMessage("not a function: INTEGER")},
+		{`"a".split(1)`, errorMessage("separator for `split` must be STRING, got INTEGER")},
+	}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+		testExpectedObject(t, evaluated, test.expected)
+	}
+}
+
+func TestNullAndOptionalChaining(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{"null", nil},
+		{"null == null", true},
+		{"let h = {}; h.missing == null", true},
+		{"[1][5] == null", true},
+		{"1 == null", false},
+		{`let h = {"a": {"b": 2}}; h?.a?.b`, 2},
+		{`let h = {"a": {"b": 2}}; h?["a"]?["b"]`, 2},
+		{`let h = null; h?.a`, nil},
+		{`let h = null; h?[0]`, nil},
+		{`let h = null; h?.a.b.c`, nil},
+		{`let h = null; h?.a[0].upper()`, nil},
+		{`let h = null; h?.upper()`, nil},
+		{`let h = {"a": null}; h.a?.b`, nil},
+		{`let h = {"n": "x"}; h?.n.upper()`, "X"},
+		{`let h = {}; h.a.b`, errorMessage("field access not supported: NULL")},
+		{`let h = {"a": null}; h?.a.b`, errorMessage("field access not supported: NULL")},
+		{"null ?? 5", 5},
+		{"0 ?? 5", 0},
+		{"false ?? true", false},
+		{"null ?? null ?? 3", 3},
+		{`let h = {}; h.limit ?? 100`, 100},
+		{`let h = null; h?.limit ?? 100`, 100},
+		{"[1]?[5] ?? 9", 9},
+		{"1 ?? missing", 1},
+		{"null ?? missing", errorMessage("identifier not found: missing")},
+		{`match (null) { null => "none", _ => "some" }`, "none"},
+		{`match (1) { null => "none", _ => "some" }`, "some"},
+	}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+		testExpectedObject(t, evaluated, test.expected)
+	}
+}
+
+func TestRegisterMethod(t *testing.T) {
+	evaluator.RegisterMethod(object.IntegerType, "double", &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			return &object.Integer{Value: args[0].(*object.Integer).Value * 2}
+		},
+	})
+
+	testIntegerObject(t, testEval("let x = 4; x.double()"), 8)
+	testIntegerObject(t, testEval("3 |> |x| x.double()"), 6)
+}
+
+func TestBlockScoping(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{"let x = 1; if (true) { let x = 2; }; x", 1},
+		{"let x = 1; if (true) { x = 2; }; x", 2},
+		{"let x = 1; if (false) { 0 } else { let x = 3; x }", 3},
+		{"let x = 1; { let x = 2; x = 3; }; x", 1},
+		{"let x = 1; { x = 5 }; x", 5},
+		{"{ let y = 2 }; y", errorMessage("identifier not found: y")},
+		{"if (true) { let y = 2 }; y", errorMessage("identifier not found: y")},
+		{"{ let y = 2; y * 2 }", 4},
+		{"const K = 1; { const K = 2; K }", 2},
+		{"const K = 1; { const K = 2; }; K", 1},
+		{"let f = fn(a) { { return a * 2 }; 0 }; f(4)", 8},
+		{"let f = fn(a) { let a = a + 1; a }; f(4)", 5},
+		{"let f = fn() { { fn g() { 3 }; g() } }; f()", 3},
+		{"let f = fn() { { fn g() { 3 } }; g() }; f()", errorMessage("identifier not found: g")},
+		{"let i = 0; while (i < 3) { let j = i; i = i + 1 }; j", errorMessage("identifier not found: j")},
+		{"let s = 0; for (i in 0..3) { let t = i * 2; s = s + t }; s", 12},
+		{"let n = 0; match (1) { 1 => { let n = 5 } }; n", 0},
+	}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+		testExpectedObject(t, evaluated, test.expected)
+	}
+}
+
+func TestTryCatch(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`try { throw "bad row" } catch (e) { e.message }`, "bad row"},
+		{`try { throw "bad row" } catch (e) { e.kind }`, "UserError"},
+		{`try { throw {"row": 2} } catch (e) { e.value.row }`, 2},
+		{`try { throw 5 } catch (e) { e.message }`, "5"},
+		{`try { 1 + "a" } catch (e) { e.message }`, "type mismatch: INTEGER + STRING"},
+		{`try { 1 + "a" } catch (e) { e.kind }`, "TypeError"},
+		{`try { 1 + "a" } catch (e) { e.value }`, nil},
+		{"try { missing } catch (e) { e.line * 10 + e.column }", 17},
+		{"try {\n  throw 1\n} catch (e) { [e.line, e.column] }", inspected("[2, 3]")},
+		{"try { len(1) } catch (e) { e.message }", "argument to `len` not supported, got INTEGER"},
+		{"try { let [a] = 1 } catch (e) { e.kind }", "PatternError"},
+		{"try { 5 } catch (e) { 0 }", 5},
+		{"try { x } catch { 0 }", 0},
+		{`let f = fn() { throw "deep" }; let g = fn() { f() + 1 }; try { g() } catch (e) { e.message }`, "deep"},
+		{`let s = 0; for (r in [1, "x", 3]) { try { s = s + r } catch { continue } }; s`, 4},
+		{`throw "oops"`, errorMessage("oops")},
+		{`try { throw "a" } catch (e) { throw e }`, errorMessage("a")},
+		{`try { throw "a" } catch (e) { throw e.message + "!" }`, errorMessage("a!")},
+		{`try { throw "a" } catch (e) { e }`, inspected("UserError: a")},
+		{`match (try { throw 1 } catch (e) { e }) { e: error => e.kind, _ => "no" }`, "UserError"},
+		{`try { throw "a" } catch (e) { e.stack }`, errorMessage("unknown field stack for ERROR_VALUE")},
+		{"let log = []; try { 1 } finally { append!(log, 1) }; log", inspected("[1]")},
+		{"let log = []; try { throw 1 } catch { append!(log, 1) } finally { append!(log, 2) }; log", inspected("[1, 2]")},
+		{"let log = []; try { try { throw 1 } finally { append!(log, 1) } } catch { append!(log, 2) }; log", inspected("[1, 2]")},
+		{"try { throw 1 } finally { 2 }", errorMessage("1")},
+		{"try { 1 } finally { throw 2 }", errorMessage("2")},
+		{"let f = fn() { try { return 1 } finally { 2 } }; f()", 1},
+		{"let f = fn() { try { return 1 } finally { return 2 } }; f()", 2},
+		{"let f = fn() { try { throw 1 } finally { return 2 } }; f()", 2},
+		{"let i = 0; while (true) { try { break } finally { i = i + 1 } }; i", 1},
+		{"try { throw 1 } catch (e) { let e = 2; e }", 2},
+	}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+		testExpectedObject(t, evaluated, test.expected)
+	}
+}
+
+func TestErrorKinds(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected object.ErrorKind
+	}{
+		{`1 + "a"`, object.TypeError},
+		{"-true", object.TypeError},
+		{"5()", object.TypeError},
+		{"for (x in 5) {}", object.TypeError},
+		{`len(1)`, object.TypeError},
+		{`"a".nope()`, object.TypeError},
+		{"missing", object.NameError},
+		{"missing = 1", object.NameError},
+		{"len()", object.ArityError},
+		{"fn f(a) { a }; f(1, 2)", object.ArityError},
+		{"fn f(a) { a }; f(b: 1)", object.ArityError},
+		{"delete!([1], 3)", object.IndexError},
+		{"let xs = [1]; xs[3] = 1", object.IndexError},
+		{"1..5 step 0", object.ValueError},
+		{"1 / 0", object.DivisionByZero},
+		{"let [a, b] = [1]", object.PatternError},
+		{`let {a} = {}`, object.PatternError},
+		{"const X = 1; let f = fn() { X = 2 }; f()", object.ImmutableError},
+		{"freeze([1])[0] = 2", object.ImmutableError},
+		{`throw "x"`, object.UserError},
+		{"...[1]", object.RuntimeError},
+	}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+		err, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error for %q. got=%T (%+v)", test.input, evaluated, evaluated)
+			continue
+		}
+		if err.ErrorKind() != test.expected {
+			t.Errorf("wrong kind for %q. want=%s, got=%s (%s)", test.input, test.expected, err.ErrorKind(), err.Message)
+		}
+
+		caught := testEval("try { " + test.input + " } catch (e) { e.kind }")
+		testStringObject(t, caught, string(test.expected))
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	env := object.NewEnvironment(nil)
+
+	result, err := evaluator.Run(parser.New(lexer.New("let x = 2; x * 3")).ParseProgram(), env)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	testIntegerObject(t, result, 6)
+
+	_, err = evaluator.Run(parser.New(lexer.New(`x + "a"`)).ParseProgram(), env)
+	if !errors.Is(err, object.TypeError) {
+		t.Errorf("expected errors.Is(err, TypeError). got=%v", err)
+	}
+	if errors.Is(err, object.NameError) {
+		t.Errorf("unexpected errors.Is(err, NameError) for %v", err)
+	}
+	var objErr *object.Error
+	if !errors.As(err, &objErr) {
+		t.Fatalf("expected errors.As(err, *object.Error). got=%T", err)
+	}
+	if objErr.Message != "type mismatch: INTEGER + STRING" {
+		t.Errorf("wrong message. got=%q", objErr.Message)
+	}
+	if err.Error() != "1:3: TypeError: type mismatch: INTEGER + STRING" {
+		t.Errorf("wrong err.Error(). got=%q", err.Error())
+	}
+
+	wrapped := fmt.Errorf("running rule: %w", err)
+	if !errors.Is(wrapped, object.TypeError) {
+		t.Errorf("expected wrapped error to be a TypeError. got=%v", wrapped)
+	}
+}
+
+func TestStackTraces(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"fn g(x) {\n  x + \"a\"\n}\nfn f(x) { g(x) }\nf(1)",
+			"TypeError: type mismatch: INTEGER + STRING\n\ng(...)\n\t2:5\nf(...)\n\t4:12\nmain\n\t5:2",
+		},
+		{
+			"let f = fn(x) {\n  len(x)\n};\n1 |> f",
+			"TypeError: argument to `len` not supported, got INTEGER\n\nlen(...)\n\t<builtin>\nf(...)\n\t2:6\nmain\n\t4:3",
+		},
+		{
+			"\"a\".upper(1)",
+			"ArityError: wrong number of arguments. got=2, want=1\n\nupper(...)\n\t<builtin>\nmain\n\t1:10",
+		},
+		{
+			"fn f() { throw \"x\" }\nfn g() {\n  try { f() } catch (e) { throw e }\n}\ng()",
+			"UserError: x\n\nf(...)\n\t1:10\ng(...)\n\t3:10\nmain\n\t5:2",
+		},
+		{
+			"fn f() { missing }\nf()",
+			"NameError: identifier not found: missing\n\nf(...)\n\t1:10\nmain\n\t2:2",
+		},
+		{
+			"fn f(a) { a }\nf()",
+			"ArityError: wrong number of arguments to `f`. got=0, want=1\n\nmain\n\t2:2",
+		},
+		{
+			"let xs = freeze([1, 2]);\n[3] |> fn(x) {\n  (fn() { xs[5] = 1 })()\n}",
+			"ImmutableError: cannot modify frozen ARRAY\n\n<anonymous>(...)\n\t3:13\n<anonymous>(...)\n\t3:23\nmain\n\t2:5",
+		},
+		{
+			"let f = fn(x) { x.y };\nf(1)",
+			"TypeError: field access not supported: INTEGER\n\nf(...)\n\t1:18\nmain\n\t2:2",
+		},
+	}
+
+	for _, test := range tests {
+		err, ok := testEval(test.input).(*object.Error)
+		if !ok {
+			t.Fatalf("expected error for %q", test.input)
+		}
+		if err.StackTrace() != test.expected {
+			t.Errorf("wrong stack trace for %q.\nwant:\n%s\ngot:\n%s", test.input, test.expected, err.StackTrace())
+		}
+	}
+}
+
+func TestPanicRecovery(t *testing.T) {
+	evaluator.RegisterMethod(object.IntegerType, "explode", &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			panic("boom")
+		},
+	})
+
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{"1.explode()", errorMessage("boom")},
+		{"let f = fn(n) { n.explode() }; f(1)", errorMessage("boom")},
+		{"try { 1.explode() } catch (e) { e.kind }", "InternalError"},
+		{"try { 1.explode() } catch (e) { e.message }", "boom"},
+		{"let xs = [1, 2]; try { xs[0].explode() } catch { len(xs) }", 2},
+	}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+		testExpectedObject(t, evaluated, test.expected)
+	}
+
+	err, ok := testEval("let f = fn(n) {\n  n.explode()\n};\nf(1)").(*object.Error)
+	if !ok {
+		t.Fatalf("expected error from explode")
+	}
+	if err.ErrorKind() != object.InternalError {
+		t.Errorf("wrong kind. want=%s, got=%s", object.InternalError, err.ErrorKind())
+	}
+	if err.Pos.String() != "2:12" {
+		t.Errorf("wrong position. want=2:12, got=%s", err.Pos)
+	}
+	if len(err.Trace) != 2 || err.Trace[0].Function != "explode" || err.Trace[1].Function != "f" {
+		t.Errorf("wrong trace. got=%+v", err.Trace)
+	}
+}
+
+func TestEvaluatorPanicRecovery(t *testing.T) {
+	// An if expression without a consequence cannot come from the parser,
+	// and makes the evaluator itself panic.
+	program := &ast.Program{Statements: []ast.Statement{
+		&ast.ExpressionStatement{
+			Token: token.Token{Type: token.IF, Literal: "if", Pos: token.Position{Line: 3, Column: 7}},
+			Expression: &ast.IfExpression{
+				Token:     token.Token{Type: token.IF, Literal: "if"},
+				Condition: &ast.Boolean{Token: token.Token{Type: token.TRUE, Literal: "true"}, Value: true},
+			},
+		},
+	}}
+
+	err, ok := evaluator.Eval(program, object.NewEnvironment(nil)).(*object.Error)
+	if !ok {
+		t.Fatalf("expected error from evaluating a malformed program")
+	}
+	if err.ErrorKind() != object.InternalError {
+		t.Errorf("wrong kind. want=%s, got=%s", object.InternalError, err.ErrorKind())
+	}
+	if err.Pos.String() != "3:7" {
+		t.Errorf("wrong position. want=3:7, got=%s", err.Pos)
+	}
+}
+
+func TestCyclicValues(t *testing.T) {
+	const cycle = "let a = [1]; append!(a, a); "
+
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{cycle + "a == a", true},
+		{cycle + "let b = [1]; append!(b, b); a == b", true},
+		{cycle + "len(sort([a, a]))", 2},
+		{cycle + `"${a}"`, "[1, [...]]"},
+		{`let h = {}; set!(h, "h", h); "${h}"`, "{h:{...}}"},
+		{cycle + "json(a)", errorMessage("cannot encode cyclic ARRAY as JSON")},
+		{cycle + "let h = {}; set!(h, a, 1)", errorMessage("unusable as hash key: ARRAY")},
+		{cycle + "freeze(a); len(a)", 2},
+	}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+		testExpectedObject(t, evaluated, test.expected)
+	}
+}
+
+func TestRecursionLimit(t *testing.T) {
+	evaluated := testEval("let f = fn(n) { f(n + 1) }; f(0)")
+	err, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+	}
+	if err.ErrorKind() != object.RecursionError {
+		t.Errorf("wrong kind. want=%s, got=%s", object.RecursionError, err.ErrorKind())
+	}
+	if err.Message != "maximum call depth exceeded: 10000" {
+		t.Errorf("wrong message. got=%q", err.Message)
+	}
+	if err.Pos.String() != "1:18" {
+		t.Errorf("wrong position. want=1:18, got=%s", err.Pos)
+	}
+
+	env := object.NewEnvironment(nil)
+	env.SetMaxCallDepth(50)
+
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{"let down = fn(n) { if (n == 0) { 0 } else { down(n - 1) } }; down(49)", 0},
+		{"down(50)", errorMessage("maximum call depth exceeded: 50")},
+		{"let odd = fn(n) { if (n == 0) { false } else { even(n - 1) } }; let even = fn(n) { if (n == 0) { true } else { odd(n - 1) } }; even(100)", errorMessage("maximum call depth exceeded: 50")},
+		{`try { down(100) } catch (e) { e.kind }`, "RecursionError"},
+		// The depth unwinds with the calls, however they ended.
+		{"down(49)", 0},
+	}
+
+	for _, test := range tests {
+		evaluated := evaluator.Eval(parser.New(lexer.New(test.input)).ParseProgram(), env)
+		testExpectedObject(t, evaluated, test.expected)
+	}
+}
+
+func TestEmptyBodies(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{"", nil},
+		{"fn() {}()", nil},
+		{"if (true) {}", nil},
+		{"let y = if (true) {}; y", nil},
+		{"let f = fn() {}; f() == null", true},
+		{"[fn() {}()]", inspected("[null]")},
+		{"{}", inspected("{}")},
+	}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+		testExpectedObject(t, evaluated, test.expected)
+	}
+
+	result, err := evaluator.Run(parser.New(lexer.New("fn() {}()")).ParseProgram(), object.NewEnvironment(nil))
+	if err != nil || result != evaluator.Null {
+		t.Errorf("Run returned (%v, %v), want null", result, err)
+	}
+}
+
+func TestEvalUnknownNode(t *testing.T) {
+	evaluated := evaluator.Eval(&ast.Identifier{}, object.NewEnvironment(nil))
+	testErrorObject(t, evaluated, "identifier not found: ")
+
+	evaluated = evaluator.Eval(&ast.ArrayPattern{}, object.NewEnvironment(nil))
+	testErrorObject(t, evaluated, "cannot evaluate *ast.ArrayPattern")
+}
+
+func TestArithmeticModes(t *testing.T) {
+	const (
+		maxInt = "9223372036854775807"
+		minInt = "(-9223372036854775807 - 1)"
+	)
+
+	tests := []struct {
+		input   string
+		wrap    any
+		checked any
+		big     any
+	}{
+		{"2 + 3 * 4", 14, 14, 14},
+		{maxInt + " + 1", int64(math.MinInt64), errorMessage("integer overflow: 9223372036854775807 + 1"), inspected("9223372036854775808")},
+		{minInt + " - 1", int64(math.MaxInt64), errorMessage("integer overflow: -9223372036854775808 - 1"), inspected("-9223372036854775809")},
+		{maxInt + " * 2", -2, errorMessage("integer overflow: 9223372036854775807 * 2"), inspected("18446744073709551614")},
+		{"-1 * " + minInt, int64(math.MinInt64), errorMessage("integer overflow: -1 * -9223372036854775808"), inspected("9223372036854775808")},
+		{minInt + " / -1", int64(math.MinInt64), errorMessage("integer overflow: -9223372036854775808 / -1"), inspected("9223372036854775808")},
+		{"-" + minInt, int64(math.MinInt64), errorMessage("integer overflow: -(-9223372036854775808)"), inspected("9223372036854775808")},
+		{"0 - " + minInt, int64(math.MinInt64), errorMessage("integer overflow: 0 - -9223372036854775808"), inspected("9223372036854775808")},
+		{"-9223372036854775808", int64(math.MinInt64), int64(math.MinInt64), int64(math.MinInt64)},
+		{"-9223372036854775808 - 1", int64(math.MaxInt64), errorMessage("integer overflow: -9223372036854775808 - 1"), inspected("-9223372036854775809")},
+		{"92233720368547758070", errorMessage("integer literal overflows int64: 92233720368547758070"), errorMessage("integer literal overflows int64: 92233720368547758070"), inspected("92233720368547758070")},
+		{"(" + maxInt + " // 2) * 4", -2, errorMessage("integer overflow: 9223372036854775807/2 * 4"), inspected("18446744073709551614")},
+		{"(" + maxInt + " // 2) * 3", inspected("27670116110564327421/2"), inspected("27670116110564327421/2"), inspected("27670116110564327421/2")},
+		{"round(" + maxInt + " // 2 * 3)", int64(-4611686018427387906), errorMessage("integer overflow: result of round"), inspected("13835058055282163710")},
+		{"(" + maxInt + " // 2 * 3).round()", int64(-4611686018427387906), errorMessage("integer overflow: result of round"), inspected("13835058055282163710")},
+		{"0x1_0000_0000_0000_0000", errorMessage("integer literal overflows int64: 0x1_0000_0000_0000_0000"), errorMessage("integer literal overflows int64: 0x1_0000_0000_0000_0000"), inspected("18446744073709551616")},
+		{"92233720368547758070 / 10", errorMessage("integer literal overflows int64: 92233720368547758070"), errorMessage("integer literal overflows int64: 92233720368547758070"), int64(math.MaxInt64)},
+		{"(" + maxInt + " + 1) - 1", int64(math.MaxInt64), errorMessage("integer overflow: 9223372036854775807 + 1"), int64(math.MaxInt64)},
+		{maxInt + " + 1 > " + maxInt, false, errorMessage("integer overflow: 9223372036854775807 + 1"), true},
+		{maxInt + " + 1 == " + maxInt + " + 1", true, errorMessage("integer overflow: 9223372036854775807 + 1"), true},
+		{"-(" + maxInt + " * 2)", 2, errorMessage("integer overflow: 9223372036854775807 * 2"), inspected("-18446744073709551614")},
+		{"(" + maxInt + " * 2) / 0", errorMessage("division by zero: -2 / 0"), errorMessage("integer overflow: 9223372036854775807 * 2"), errorMessage("division by zero: 18446744073709551614 / 0")},
+	}
+
+	modes := []object.ArithmeticMode{object.WrapArithmetic, object.CheckedArithmetic, object.BigArithmetic}
+	for _, test := range tests {
+		for i, expected := range []any{test.wrap, test.checked, test.big} {
+			env := object.NewEnvironment(nil)
+			env.SetArithmetic(modes[i])
+			evaluated := evaluator.Eval(parser.New(lexer.New(test.input)).ParseProgram(), env)
+
+			testExpectedObject(t, evaluated, expected)
+		}
+	}
+}
+
+func TestBigIntValues(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let big = 9223372036854775807 + 1; json([big, 1])", "[9223372036854775808,1]"},
+		{"let big = 9223372036854775807 + 1; json(sort([big, 1, -big, 0]))", "[-9223372036854775808,0,1,9223372036854775808]"},
+		{"let big = 9223372036854775807 + 1; let h = {big: \"a\"}; h[9223372036854775807 + 1]", "a"},
+		{"let big = 9223372036854775807 * 4; match (big) { n: int => \"int\", _ => \"other\" }", "int"},
+		{"let big = 9223372036854775807 * 4; try { big + \"a\" } catch (e) { e.message }", "type mismatch: BIGINT + STRING"},
+		{"json([1, 2, 3][-99999999999999999999:99999999999999999999])", "[1,2,3]"},
+		{`"abc"[1:99999999999999999999]`, "bc"},
+		{"match (99999999999999999999) { 0..10 => \"small\", _ => \"big\" }", "big"},
+	}
+
+	for _, test := range tests {
+		env := object.NewEnvironment(nil)
+		env.SetArithmetic(object.BigArithmetic)
+		evaluated := evaluator.Eval(parser.New(lexer.New(test.input)).ParseProgram(), env)
+		testStringObject(t, evaluated, test.expected)
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"[1, 2, 3][99999999999999999999]", "index out of range: 99999999999999999999 with length 3"},
+		{`"héllo"[-99999999999999999999]`, "index out of range: -99999999999999999999 with length 5"},
+		{"let a = [1]; a[99999999999999999999] = 2", "index out of range: 99999999999999999999 with length 1"},
+		{"delete!([1], 99999999999999999999)", "index out of range: 99999999999999999999 with length 1"},
+		{"1..99999999999999999999", "range bounds must fit in int64, got 99999999999999999999"},
+		{"0..10 step 99999999999999999999", "range step must fit in int64, got 99999999999999999999"},
+	}
+
+	for _, test := range errorTests {
+		env := object.NewEnvironment(nil)
+		env.SetArithmetic(object.BigArithmetic)
+		evaluated := evaluator.Eval(parser.New(lexer.New(test.input)).ParseProgram(), env)
+		err, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("object is not Error for %q. got=%T (%+v)", test.input, evaluated, evaluated)
+			continue
+		}
+		if err.Message != test.expected {
+			t.Errorf("wrong error message for %q. want=%q, got=%q", test.input, test.expected, err.Message)
+		}
+		if k := err.ErrorKind(); k != object.IndexError && k != object.ValueError {
+			t.Errorf("wrong error kind for %q. got=%s", test.input, k)
+		}
+	}
+}
+
+func TestRationals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{"7 // 30", inspected("7/30")},
+		{"14 // 60", inspected("7/30")},
+		{"-7 // 30", inspected("-7/30")},
+		{"7 // -30", inspected("-7/30")},
+		{"7 // 30 * 30", 7},
+		{"3000 * 7 // 30", 700},
+		{"ratio(4, 2)", 2},
+		{"ratio(1, 3) + ratio(1, 6)", inspected("1/2")},
+		{"1 // 3 - 1", inspected("-2/3")},
+		{"-(1 // 3)", inspected("-1/3")},
+		{"(1 // 2) / (1 // 4)", 2},
+		{"7 / (1 // 2)", 14},
+		{"1 // 3 < 1 // 2", true},
+		{"1 // 2 > 0", true},
+		{"1 // 2 == ratio(2, 4)", true},
+		{"1 // 2 != 1", true},
+		{"(9223372036854775807 // 2) * 3", inspected("27670116110564327421/2")},
+		{`{1 // 2: "half"}[2 // 4]`, "half"},
+		{"sort([1, 1 // 2, -1 // 3, 0])[1]", 0},
+		{"match (1 // 2) { r: rational => r * 2, _ => 0 }", 1},
+		{"25e-2", inspected("1/4")},
+		{"25e-2 * 4", 1},
+		{"1e3", 1000},
+		{"1e30", errorMessage("integer literal overflows int64: 1e30")},
+		{"1 // 0", errorMessage("division by zero: 1 // 0")},
+		{"(1 // 2) / 0", errorMessage("division by zero: 1/2 / 0")},
+		{`1 // 3 + "a"`, errorMessage("type mismatch: RATIONAL + STRING")},
+		{`ratio(1, "a")`, errorMessage("argument to `ratio` not supported, got STRING")},
+		{"ratio(1)", errorMessage("wrong number of arguments. got=1, want=2")},
+	}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+		testExpectedObject(t, evaluated, test.expected)
+	}
+}
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected map[string]int64
+	}{
+		{"5 // 2", map[string]int64{"half_even": 2, "half_up": 3, "half_down": 2, "floor": 2, "ceil": 3, "down": 2, "up": 3}},
+		{"7 // 2", map[string]int64{"half_even": 4, "half_up": 4, "half_down": 3, "floor": 3, "ceil": 4, "down": 3, "up": 4}},
+		{"-5 // 2", map[string]int64{"half_even": -2, "half_up": -3, "half_down": -2, "floor": -3, "ceil": -2, "down": -2, "up": -3}},
+		{"7 // 3", map[string]int64{"half_even": 2, "half_up": 2, "half_down": 2, "floor": 2, "ceil": 3, "down": 2, "up": 3}},
+		{"-8 // 3", map[string]int64{"half_even": -3, "half_up": -3, "half_down": -3, "floor": -3, "ceil": -2, "down": -2, "up": -3}},
+		{"6", map[string]int64{"half_even": 6, "half_up": 6, "half_down": 6, "floor": 6, "ceil": 6, "down": 6, "up": 6}},
+	}
+
+	for _, test := range tests {
+		for mode, expected := range test.expected {
+			input := fmt.Sprintf("round(%s, mode: %q)", test.input, mode)
+			testIntegerObject(t, testEval(input), expected)
+		}
+	}
+
+	testIntegerObject(t, testEval("round(5 // 2)"), 2)
+	testIntegerObject(t, testEval(`round(5 // 2, "half_up")`), 3)
+	testIntegerObject(t, testEval(`(7 // 3).round(mode: "ceil")`), 3)
+	testIntegerObject(t, testEval("let fee = 3000; round(fee * 7 // 31)"), 677)
+	testErrorObject(t, testEval(`round(1 // 3, mode: "nope")`), "unknown rounding mode: nope")
+	testErrorObject(t, testEval(`round(1 // 3, by: "up")`), "unknown keyword argument by to `round`")
+	testErrorObject(t, testEval(`round("a")`), "argument to `round` not supported, got STRING")
+}
+
+func TestStringInterpolation(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`let total = 42; "Total: ${total}"`, "Total: 42"},
+		{`let customer = {"name": "Ann"}; "for ${customer.name}!"`, "for Ann!"},
+		{`"${1 + 2}${"b"}${[1, "a"]}${null}"`, "3b[1, a]null"},
+		{`let n = 2; "${"x${n * 2}y"}"`, "x4y"},
+		{`let amount = 1234 // 100; "${amount:.2f}"`, "12.34"},
+		{`"${1 // 3:.4f} ${2 // 3:.0f} ${-5 // 2:.0f} ${7:f}"`, "0.3333 1 -3 7.000000"},
+		{`"${255:x} ${255:X} ${8:o} ${5:b} ${-42:d}"`, "ff FF 10 101 -42"},
+		{`"[${42:5}|${42:<5}|${"ab":5}|${"ab":>5}|${"ab":*^6}|${7:05}|${-7:05d}|${12345:3}]"`, "[   42|42   |ab   |   ab|**ab**|00007|-0007|12345]"},
+		{`"${42:s}|${42:4s}|"`, "42|42  |"},
+		{`"${x}"`, errorMessage("identifier not found: x")},
+		{`"${"a":d}"`, errorMessage(`cannot format STRING with "d"`)},
+		{`"${[1]:f}"`, errorMessage(`cannot format ARRAY with "f"`)},
+		{`"${1:.2}"`, errorMessage(`invalid format spec ".2": precision requires the f verb`)},
+		{`"${1:.2d}"`, errorMessage(`invalid format spec ".2d": precision requires the f verb`)},
+		{`"${1:q}"`, errorMessage(`invalid format spec "q"`)},
+		{`"${1:.f}"`, errorMessage(`invalid format spec ".f"`)},
+	}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+		testExpectedObject(t, evaluated, test.expected)
+	}
+}
+
+func TestConstBindings(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{"const X = 5; X", 5},
+		{"const X = 5; let f = fn() { let X = 1; X }; f() + X", 6},
+		{"let f = fn() { const K = 2; K }; f() + f()", 4},
+		{"let i = 0; while (i < 3) { const c = i; i = i + 1 }; i", 3},
+		{"let f = fn() { X = 2 }; const X = 1; f()", errorMessage("cannot assign to constant: X")},
+		{"let f = fn() { X = 2 }; const X = 1; f(); X", errorMessage("cannot assign to constant: X")},
+		{"let f = fn() { X }; const X = 1; f()", 1},
+		{"y = 1", errorMessage("identifier not found: y")},
+	}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+		testExpectedObject(t, evaluated, test.expected)
+	}
+}
+
+func TestConstAcrossPrograms(t *testing.T) {
+	env := object.NewEnvironment(nil)
+	evaluator.Eval(parser.New(lexer.New("const TAX_RATE = 20;")).ParseProgram(), env)
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"TAX_RATE = 5", "cannot assign to constant: TAX_RATE"},
+		{"let TAX_RATE = 5", "cannot redeclare constant: TAX_RATE"},
+		{"const TAX_RATE = 5", "cannot redeclare constant: TAX_RATE"},
+		{"fn TAX_RATE() {}", "cannot redeclare constant: TAX_RATE"},
+		{"let [TAX_RATE] = [5]", "cannot redeclare constant: TAX_RATE"},
+	}
+
+	for _, test := range tests {
+		program := parser.New(lexer.New(test.input)).ParseProgram()
+		testErrorObject(t, evaluator.Eval(program, env), test.expected)
+	}
+
+	testIntegerObject(t, evaluator.Eval(parser.New(lexer.New("TAX_RATE")).ParseProgram(), env), 20)
+}
+
+func TestFreeze(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{"let xs = freeze([1, 2]); xs[0]", 1},
+		{"let xs = freeze([1, 2]); len(push(xs, 3))", 3},
+		{"let xs = freeze([1, 2]); xs[0] = 5", errorMessage("cannot modify frozen ARRAY")},
+		{"let xs = freeze([1, [2]]); append!(xs[1], 3)", errorMessage("cannot modify frozen ARRAY")},
+		{"let xs = freeze([1]); set!(xs, 0, 2)", errorMessage("cannot modify frozen ARRAY")},
+		{"let xs = freeze([1]); delete!(xs, 0)", errorMessage("cannot modify frozen ARRAY")},
+		{`let h = freeze({"a": {"b": 1}}); h.a.b = 2`, errorMessage("cannot modify frozen HASH")},
+		{`let h = freeze({"a": 1}); h["b"] = 2`, errorMessage("cannot modify frozen HASH")},
+		{`let h = freeze({"a": 1}); delete!(h, "a")`, errorMessage("cannot modify frozen HASH")},
+		{`let h = freeze({"a": 1}); h.a`, 1},
+		{"freeze(5)", 5},
+		{"let xs = [1]; let ys = [xs]; freeze(ys); xs[0] = 2", errorMessage("cannot modify frozen ARRAY")},
+	}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+		testExpectedObject(t, evaluated, test.expected)
+	}
+}
+
+func TestFrozenMutationPosition(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let xs = freeze([1]);\nxs[0] = 2", "2:3"},
+		{"let xs = freeze([1]);\n  append!(xs, 2)", "2:10"},
+	}
+
+	for _, test := range tests {
+		err, ok := testEval(test.input).(*object.Error)
+		if !ok {
+			t.Fatalf("expected error for %q", test.input)
+		}
+		if err.Pos.String() != test.expected {
+			t.Errorf("wrong position for %q. want=%s, got=%s", test.input, test.expected, err.Pos)
+		}
+	}
+}
+
+func TestIndexAndRangeErrorPositions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[1, 2, 3][0..5]", "1:10"},
+		{"let s = \"abc\";\ns[\"a\"]", "2:2"},
+		{`[1, 2][:"a"]`, "1:7"},
+		{"0..10 step 0", "1:2"},
+		{"let f = fn() {};\n  {f: 1}", "2:3"},
+	}
+
+	for _, test := range tests {
+		err, ok := testEval(test.input).(*object.Error)
+		if !ok {
+			t.Fatalf("expected error for %q", test.input)
+		}
+		if err.Pos.String() != test.expected {
+			t.Errorf("wrong position for %q. want=%s, got=%s", test.input, test.expected, err.Pos)
+		}
+	}
+}
+
+func TestClosures(t *testing.T) {
+	input := `
+	let newAdder = fn(x) {
+		fn(y) { x + y };
+	};
+
+	let addTwo = newAdder(2);
+	addTwo(2);
+	`
+
+	testIntegerObject(t, testEval(input), 4)
+}
+
+func TestStringLiteral(t *testing.T) {
+	input := `"Hello World!"`
+
+	evaluated := testEval(input)
+	str, ok := evaluated.(*object.String)
+	if !ok {
+		t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	if str.Value != "Hello World!" {
+		t.Errorf("String has wrong value. got=%q", str.Value)
+	}
+}
+
+func TestStringConcatentation(t *testing.T) {
+	input := `"Hello" + " " + "World!"`
+
+	evaluated := testEval(input)
+	str, ok := evaluated.(*object.String)
+	if !ok {
+		t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	if str.Value != "Hello World!" {
+		t.Errorf("String has wrong value. got=%q", str.Value)
+	}
+}
+
+func TestBuiltinFunctions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{{
+		input:    `len("")`,
+		expected: 0,
+	}, {
+		input:    `len("four")`,
+		expected: 4,
+	}, {
+		input:    `len("hello world")`,
+		expected: 11,
+	}, {
+		input:    `len(1)`,
+		expected: "argument to `len` not supported, got INTEGER",
+	}, {
+		input:    `len("one", "two")`,
+		expected: "wrong number of arguments. got=2, want=1",
+	}}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+		switch expected := test.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", errObj, errObj)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestArrayLiterals(t *testing.T) {
+	input := "[1, 2* 2, 3 + 3]"
+
+	evaluated := testEval(input)
+	result, ok := evaluated.(*object.Array)
+	if !ok {
+		t.Fatalf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	if len(result.Elements) != 3 {
+		t.Fatalf("array has wrong num of elements. got=%d", len(result.Elements))
+	}
+
+	testIntegerObject(t, result.Elements[0], 1)
+	testIntegerObject(t, result.Elements[1], 4)
+	testIntegerObject(t, result.Elements[2], 6)
+}
+
+func TestArrayIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{{
+		input:    "[1, 2, 3][0]",
+		expected: 1,
+	}, {
+		input:    "[1, 2, 3][1]",
+		expected: 2,
+	}, {
+		input:    "[1, 2, 3][2]",
+		expected: 3,
+	}, {
+		input:    "let i = 0; [1][i]",
+		expected: 1,
+	}, {
+		input:    "[1, 2, 3][1 + 1]",
+		expected: 3,
+	}, {
+		input:    "let myArray = [1, 2, 3]; myArray[2]",
+		expected: 3,
+	}, {
+		input:    "let myArray = [1, 2, 3]; myArray[0] + myArray[1] + myArray[2];",
+		expected: 6,
+	}, {
+		input:    "let myArray = [1, 2, 3]; let i = myArray[0]; myArray[i]",
+		expected: 2,
+	}, {
+		input:    "[1, 2, 3][3]",
+		expected: nil,
+	}, {
+		input:    "[1, 2, 3][-1]",
+		expected: 3,
+	}, {
+		input:    "[1, 2, 3][-3]",
+		expected: 1,
+	}, {
+		input:    "[1, 2, 3][-4]",
+		expected: nil,
+	}}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+		integer, ok := test.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestHashLiterals(t *testing.T) {
+	input := `let two = "two";
+	{
+		"one": 10 - 9,
+		two: 1 + 1,
+		"thr" + "ee": 6 / 2,
+		4: 4,
+		true: 5,
+		false: 6
+	}`
+
+	evaluated := testEval(input)
+	result, ok := evaluated.(*object.Hash)
+	if !ok {
+		t.Fatalf("Eval didn't return Hash. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	expected := []struct {
+		key   object.Hashable
+		value int64
+	}{
+		{&object.String{Value: "one"}, 1},
+		{&object.String{Value: "two"}, 2},
+		{&object.String{Value: "three"}, 3},
+		{&object.Integer{Value: 4}, 4},
+		{evaluator.True, 5},
+		{evaluator.False, 6},
+	}
+
+	if result.Len() != len(expected) {
+		t.Fatalf("Hash has wrong num of pairs. got=%d", result.Len())
+	}
+
+	pairs := result.Pairs()
+	for i, expected := range expected {
+		pair, ok := result.Get(expected.key)
+		if !ok {
+			t.Errorf("no pair for given key in Pairs")
+		}
+		testIntegerObject(t, pair.Value, expected.value)
+
+		if pairs[i].Key.Inspect() != expected.key.Inspect() {
+			t.Errorf("pair %d out of order. want key=%s, got=%s", i, expected.key.Inspect(), pairs[i].Key.Inspect())
+		}
+	}
+}
+
+func TestHashInsertionOrder(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"z": 1, "a": 2, 3: 3, true: 4}`, "{z:1, a:2, 3:3, true:4}"},
+		{`{"a": 1, "b": 2, "a": 3}`, "{a:3, b:2}"},
+		{`let h = {"b": 1}; h["a"] = 2; h["b"] = 3; h`, "{b:3, a:2}"},
+		{`let h = {"a": 1, "b": 2}; delete!(h, "a"); h["a"] = 3; h`, "{b:2, a:3}"},
+		{`keys({"z": 1, "y": 2, "x": 3})`, "[z, y, x]"},
+		{`{"z": 1, "y": 2, "x": 3}.values()`, "[1, 2, 3]"},
+		{`let out = []; for (k in {"z": 1, "y": 2, "x": 3}) { append!(out, k) }; out`, "[z, y, x]"},
+		{`let {b, ...rest} = {"c": 1, "b": 2, "a": 3}; rest`, "{c:1, a:3}"},
+		{`let n = 0; let next = fn() { n = n + 1 }; {next(): next(), next(): next()}`, "{1:2, 3:4}"},
+		{`json({"z": 1, "a": [true, null, "x"], 2: {"n": 0..2}})`, `{"z":1,"a":[true,null,"x"],"2":{"n":[0,1,2]}}`},
+		{"json(\"a\nb\")", `"a\nb"`},
+	}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+		if evaluated.Inspect() != test.expected {
+			t.Errorf("wrong result for %q. want=%s, got=%s", test.input, test.expected, evaluated.Inspect())
+		}
+	}
+
+	testErrorObject(t, testEval(`json({"f": fn() {}})`), "cannot encode FUNCTION as JSON")
+	testErrorObject(t, testEval(`keys([1])`), "argument to `keys` not supported, got ARRAY")
+}
+
+func TestCompositeHashKeys(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`let h = {[1, "GBP"]: 10}; h[[1, "GBP"]]`, 10},
+		{`let h = {[1, "GBP"]: 10}; h[["GBP", 1]]`, nil},
+		{`let h = {[[1, 2], 3]: 1}; h[[[1, 2], 3]]`, 1},
+		{`let h = {}; h[[1, "GBP"]] = 5; h[[1, "GBP"]] = h[[1, "GBP"]] + 1; h[[1, "GBP"]]`, 6},
+		{`let k = [1]; let h = {k: 1}; append!(k, 2); h[[1]]`, 1},
+		{`let k = [1]; let h = {k: 1}; append!(k, 2); h[k]`, nil},
+		{`{0..3: 1}[0..3]`, 1},
+		{`{0..3: 1}[0..<3]`, nil},
+		{`{[1]: 1, [1]: 2}.len()`, 1},
+		{`let h = {[1]: 1, [2]: 2}; delete!(h, [1]); h.len()`, 1},
+		{`{1: 1, "1": 2, [1]: 3}.len()`, 3},
+		{`{[1, fn() {}]: 1}`, errorMessage("unusable as hash key: ARRAY")},
+		{`{"a": 1}[{"a": 1}]`, errorMessage("unusable as hash key: HASH")},
+		{`let h = {[1, 2]: 1}; for (k in h) { k[0] = 7 }`, errorMessage("cannot modify frozen ARRAY")},
+		{`let h = {[1, 2]: 1}; append!(keys(h)[0], 3)`, errorMessage("cannot modify frozen ARRAY")},
+		{`let h = {[[1], 2]: 1}; keys(h)[0][0][0] = 7`, errorMessage("cannot modify frozen ARRAY")},
+		{`let h = {[1, 2]: 1}; try { append!(keys(h)[0], 3) } catch {}; h[[1, 2]]`, 1},
+		{`let k = [1]; let h = {k: 1}; append!(k, 2); len(k)`, 2},
+	}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+		testExpectedObject(t, evaluated, test.expected)
+	}
+}
+
+func TestStructuralEquality(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`"a" == "a"`, true},
+		{`"a" != "b"`, true},
+		{`"a" < "b"`, true},
+		{`"b" > "ab"`, true},
+		{`"" < "a"`, true},
+		{`[1, 2] == [1, 2]`, true},
+		{`[1, 2] == [2, 1]`, false},
+		{`[1, [2, "x"]] == [1, [2, "x"]]`, true},
+		{`[1, 2] != [1, 2, 3]`, true},
+		{`[1, 2] < [1, 3]`, true},
+		{`[1, 2] < [1, 2, 0]`, true},
+		{`{"a": 1, "b": [2]} == {"b": [2], "a": 1}`, true},
+		{`{"a": 1} == {"a": 2}`, false},
+		{`{"a": 1} == {"a": 1, "b": 2}`, false},
+		{`0..3 == 0..3`, true},
+		{`0..3 == 0..<3`, false},
+		{`false < true`, true},
+		{`null == null`, true},
+		{`1 == "1"`, false},
+		{`[1] == 1`, false},
+		{`let f = fn() {}; f == f`, true},
+		{`fn() {} == fn() {}`, false},
+		{`match ([1, 2]) { [1, 2] => true, _ => false }`, true},
+		{`match ({"a": 1}) { {a: 1} => true, _ => false }`, true},
+		{`"a" < 1`, errorMessage("type mismatch: STRING < INTEGER")},
+		{`fn() {} < fn() {}`, errorMessage("unknown operator: FUNCTION < FUNCTION")},
+		{`[1] + [2]`, errorMessage("unknown operator: ARRAY + ARRAY")},
+	}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+		testExpectedObject(t, evaluated, test.expected)
+	}
+}
+
+func TestSort(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`sort([3, 1, 2])`, "[1, 2, 3]"},
+		{`["b", "c", "a"].sort()`, "[a, b, c]"},
+		{`sort([[2, 1], [1, 2], [1]])`, "[[1], [1, 2], [2, 1]]"},
+		{`sort(["a", 1, null, true, [0]])`, "[null, true, 1, a, [0]]"},
+		{`let xs = [2, 1]; sort(xs); xs`, "[2, 1]"},
+		{`sort([])`, "[]"},
+	}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+		if evaluated.Inspect() != test.expected {
+			t.Errorf("wrong result for %q. want=%s, got=%s", test.input, test.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestHashIndexExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{{
+		input:    `{"foo": 5}["foo"]`,
+		expected: 5,
+	}, {
+		input:    `{"foo": 5}["bar"]`,
+		expected: nil,
+	}, {
+		input:    `let key = "foo"; {"foo": 5}[key]`,
+		expected: 5,
+	}, {
+		input:    `{}["foo"]`,
+		expected: nil,
+	}, {
+		input:    `{5: 5}[5]`,
+		expected: 5,
+	}, {
+		input:    `{true: 5}[true]`,
+		expected: 5,
+	}, {
+		input:    `{false: 5}[false]`,
+		expected: 5,
 	}}
 
 	for _, test := range tests {
@@ -586,6 +1886,416 @@ func TestHashIndexExpression(t *testing.T) {
 	}
 }
 
+func TestIndexAssignment(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{{
+		input:    "let a = [1, 2, 3]; a[0] = 5; a[0]",
+		expected: 5,
+	}, {
+		input:    "let a = [1, 2, 3]; a[1] = a[1] * 10",
+		expected: 20,
+	}, {
+		input:    "let a = [1, 2]; let b = [3, 4]; a[0] = b[1] = 9; a[0] + b[1]",
+		expected: 18,
+	}, {
+		input:    `let h = {}; h["total"] = 5; h["total"]`,
+		expected: 5,
+	}, {
+		input:    `let h = {"total": 1}; h["total"] = h["total"] + 1; h["total"]`,
+		expected: 2,
+	}, {
+		input:    `let a = [1]; let b = a; b[0] = 7; a[0]`,
+		expected: 7,
+	}, {
+		input:    `let h = {}; let add = fn(k, v) { h[k] = v }; add("x", 3); h["x"]`,
+		expected: 3,
+	}, {
+		input:    `let counter = fn() { let s = [0]; [fn() { s[0] = s[0] + 1 }, fn() { s[0] }] }; let c = counter(); c[0](); c[0](); c[1]()`,
+		expected: 2,
+	}, {
+		input:    "let a = [1, 2, 3]; a[3] = 1",
+		expected: errorMessage("index out of range: 3 with length 3"),
+	}, {
+		input:    `let a = [1]; a["x"] = 1`,
+		expected: errorMessage("array index must be INTEGER, got STRING"),
+	}, {
+		input:    `let h = {}; h[fn(x) { x }] = 1`,
+		expected: errorMessage("unusable as hash key: FUNCTION"),
+	}, {
+		input:    `let s = "abc"; s[0] = "z"`,
+		expected: errorMessage("index assignment not supported: STRING"),
+	}}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+		testExpectedObject(t, evaluated, test.expected)
+	}
+}
+
+func TestMutatingBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{{
+		input:    "let a = [1]; append!(a, 2, 3); len(a)",
+		expected: 3,
+	}, {
+		input:    "let a = [1]; let b = a; append!(a, 2); last(b)",
+		expected: 2,
+	}, {
+		input:    "let a = [1, 2, 3]; set!(a, 1, 9); a[1]",
+		expected: 9,
+	}, {
+		input:    `let h = {}; set!(h, "a", 1)["a"]`,
+		expected: 1,
+	}, {
+		input:    "let a = [1, 2, 3]; delete!(a, 0); first(a)",
+		expected: 2,
+	}, {
+		input:    `let h = {"a": 1}; delete!(h, "a"); h["a"]`,
+		expected: nil,
+	}, {
+		input:    "let a = [1]; push(a, 2); len(a)",
+		expected: 1,
+	}, {
+		input:    "append!(1, 2)",
+		expected: errorMessage("argument to `append!` not supported, got INTEGER"),
+	}, {
+		input:    "let a = [1]; delete!(a, 5)",
+		expected: errorMessage("index out of range: 5 with length 1"),
+	}, {
+		input:    "set!([], 0)",
+		expected: errorMessage("wrong number of arguments. got=2, want=3"),
+	}}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+		testExpectedObject(t, evaluated, test.expected)
+	}
+}
+
+func TestWhileLoops(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{{
+		input:    "let i = 0; while (i < 10) { i = i + 1; }; i",
+		expected: 10,
+	}, {
+		input:    "let i = 0; let sum = 0; while (i < 100000) { i = i + 1; sum = sum + i; }; sum",
+		expected: 5000050000,
+	}, {
+		input:    "let i = 0; while (true) { i = i + 1; if (i == 5) { break; } }; i",
+		expected: 5,
+	}, {
+		input:    "let i = 0; let odd = 0; while (i < 10) { i = i + 1; if (i / 2 * 2 == i) { continue; } odd = odd + 1; }; odd",
+		expected: 5,
+	}, {
+		input:    "let i = 0; let n = 0; while (i < 3) { i = i + 1; let j = 0; while (true) { j = j + 1; if (j > 2) { break; } n = n + 1; } }; n",
+		expected: 6,
+	}, {
+		input:    "let f = fn() { let i = 0; while (true) { i = i + 1; if (i == 3) { return i * 10; } } }; f()",
+		expected: 30,
+	}, {
+		input:    "let i = 0; while (i < 3) { i = i + 1; fn() { 1 }(); }; i",
+		expected: 3,
+	}, {
+		input:    "while (false) { 1 }",
+		expected: nil,
+	}, {
+		input:    "x = 1",
+		expected: errorMessage("identifier not found: x"),
+	}, {
+		input:    "while (1 + true) { 1 }",
+		expected: errorMessage("type mismatch: INTEGER + BOOLEAN"),
+	}}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+		testExpectedObject(t, evaluated, test.expected)
+	}
+}
+
+func TestForLoops(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{{
+		input:    "let sum = 0; for (x in [1, 2, 3]) { sum = sum + x }; sum",
+		expected: 6,
+	}, {
+		input:    "let sum = 0; for (i, x in [10, 20, 30]) { sum = sum + i * x }; sum",
+		expected: 80,
+	}, {
+		input:    `let sum = 0; for (k in {"a": 1, "b": 2}) { sum = sum + len(k) }; sum`,
+		expected: 2,
+	}, {
+		input:    `let sum = 0; for (k, v in {"a": 1, "b": 2}) { sum = sum + v }; sum`,
+		expected: 3,
+	}, {
+		input:    `let n = 0; for (ch in "héllo") { n = n + 1 }; n`,
+		expected: 5,
+	}, {
+		input:    `let out = ""; for (ch in "abc") { out = ch + out }; out`,
+		expected: "cba",
+	}, {
+		input:    "let sum = 0; for (i in 1..100000) { sum = sum + i }; sum",
+		expected: 5000050000,
+	}, {
+		input:    "let n = 0; for (i in 5..1) { n = n + 1 }; n",
+		expected: 0,
+	}, {
+		input:    "let sum = 0; for (i in 0..10) { if (i == 5) { break; } sum = sum + i }; sum",
+		expected: 10,
+	}, {
+		input:    "let sum = 0; for (i in 0..10) { if (i / 2 * 2 == i) { continue; } sum = sum + i }; sum",
+		expected: 25,
+	}, {
+		input:    "let fns = []; for (i in 1..3) { append!(fns, fn() { i }) }; fns[0]() + fns[2]()",
+		expected: 4,
+	}, {
+		input:    "let a = [1, 2]; for (x in a) { append!(a, x) }; len(a)",
+		expected: 4,
+	}, {
+		input:    "let find = fn(xs, t) { for (i, x in xs) { if (x == t) { return i } }; -1 }; find([4, 5, 6], 6)",
+		expected: 2,
+	}, {
+		input:    "for (x in 5) { x }",
+		expected: errorMessage("not iterable: INTEGER"),
+	}, {
+		input:    `for (x in 1.."a") { x }`,
+		expected: errorMessage("range bounds must be INTEGER, got INTEGER..STRING"),
+	}}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+		testExpectedObject(t, evaluated, test.expected)
+	}
+}
+
+func TestRanges(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{{
+		input:    "1..10",
+		expected: inspected("1..10"),
+	}, {
+		input:    "0..<n step 2",
+		expected: errorMessage("identifier not found: n"),
+	}, {
+		input:    "let n = 4; 0..<n step 2",
+		expected: inspected("0..<4 step 2"),
+	}, {
+		input:    "len(1..10)",
+		expected: 10,
+	}, {
+		input:    "len(0..<10)",
+		expected: 10,
+	}, {
+		input:    "len(0..10 step 3)",
+		expected: 4,
+	}, {
+		input:    "len(0..<9 step 3)",
+		expected: 3,
+	}, {
+		input:    "len(10..1)",
+		expected: 0,
+	}, {
+		input:    "len(10..1 step -1)",
+		expected: 10,
+	}, {
+		input:    "len(0..<0)",
+		expected: 0,
+	}, {
+		input:    "len(0..9223372036854775807)",
+		expected: 9223372036854775807,
+	}, {
+		input:    "let sum = 0; for (i in 0..<10 step 3) { sum = sum + i }; sum",
+		expected: 18,
+	}, {
+		input:    "let sum = 0; for (i in 3..1 step -1) { sum = sum * 10 + i }; sum",
+		expected: 321,
+	}, {
+		input:    "let sum = 0; for (i, x in 10..12) { sum = sum + i }; sum",
+		expected: 3,
+	}, {
+		input:    "1..5 step 0",
+		expected: errorMessage("range step must not be zero"),
+	}, {
+		input:    `1..5 step "a"`,
+		expected: errorMessage("range step must be INTEGER, got STRING"),
+	}, {
+		input:    `"a"..<1`,
+		expected: errorMessage("range bounds must be INTEGER, got STRING..<INTEGER"),
+	}}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+		testExpectedObject(t, evaluated, test.expected)
+	}
+}
+
+func TestSliceExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{{
+		input:    "[1, 2, 3, 4][1:3]",
+		expected: inspected("[2, 3]"),
+	}, {
+		input:    "[1, 2, 3, 4][:-1]",
+		expected: inspected("[1, 2, 3]"),
+	}, {
+		input:    "[1, 2, 3, 4][-2:]",
+		expected: inspected("[3, 4]"),
+	}, {
+		input:    "[1, 2, 3, 4][:]",
+		expected: inspected("[1, 2, 3, 4]"),
+	}, {
+		input:    "[1, 2, 3, 4][3:1]",
+		expected: inspected("[]"),
+	}, {
+		input:    "[1, 2, 3, 4][:100]",
+		expected: inspected("[1, 2, 3, 4]"),
+	}, {
+		input:    "let a = [1, 2, 3]; let b = a[:]; b[0] = 9; a",
+		expected: inspected("[1, 2, 3]"),
+	}, {
+		input:    "[1, 2, 3, 4][1..2]",
+		expected: inspected("[2, 3]"),
+	}, {
+		input:    "[1, 2, 3, 4][0..<4 step 2]",
+		expected: inspected("[1, 3]"),
+	}, {
+		input:    "[1, 2, 3, 4][3..0 step -1]",
+		expected: inspected("[4, 3, 2, 1]"),
+	}, {
+		input:    `"hello world"[2:5]`,
+		expected: "llo",
+	}, {
+		input:    `"hello"[:-1]`,
+		expected: "hell",
+	}, {
+		input:    `"hello"[1]`,
+		expected: "e",
+	}, {
+		input:    `"hello"[-1]`,
+		expected: "o",
+	}, {
+		input:    `"hello"[1..3]`,
+		expected: "ell",
+	}, {
+		input:    `"héllo"[1]`,
+		expected: inspected("é"),
+	}, {
+		input:    `"héllo"[-4]`,
+		expected: inspected("é"),
+	}, {
+		input:    `"日本語です"[1:3]`,
+		expected: inspected("本語"),
+	}, {
+		input:    `"héllo"[0..<5 step 2]`,
+		expected: "hlo",
+	}, {
+		input:    `len("héllo")`,
+		expected: 5,
+	}, {
+		input:    `let s = "héllo"; let out = ""; for (i, c in s) { if (s[i] != c) { out = out + "!" } else { out = out + c } }; out`,
+		expected: inspected("héllo"),
+	}, {
+		input:    `let last = 0; for (i, c in "héllo") { last = i }; last`,
+		expected: 4,
+	}, {
+		input:    "let a = [1, 2, 3]; a[-1] = 9; a",
+		expected: inspected("[1, 2, 9]"),
+	}, {
+		input:    "[1, 2][0..5]",
+		expected: errorMessage("index out of range: 2 with length 2"),
+	}, {
+		input:    `[1, 2]["a":]`,
+		expected: errorMessage("slice bounds must be INTEGER, got STRING"),
+	}, {
+		input:    `{"a": 1}[0:1]`,
+		expected: errorMessage("slice operator not supported: HASH"),
+	}}
+
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+		testExpectedObject(t, evaluated, test.expected)
+	}
+	testNullObject(t, testEval(`"abc"[3]`))
+}
+
+// errorMessage marks an expected value in a test table as the message of an
+// *object.Error rather than the value of an *object.String.
+type errorMessage string
+
+// inspected marks an expected value in a test table as the Inspect() of a
+// result with no simpler form to compare against, such as an array.
+type inspected string
+
+// testExpectedObject checks obj against an expected value from a test table:
+// an int, int64 or bool, a string for an *object.String, an inspected
+// rendering, an errorMessage, or nil for null.
+func testExpectedObject(t *testing.T, obj object.Object, expected any) bool {
+	switch expected := expected.(type) {
+	case int:
+		return testIntegerObject(t, obj, int64(expected))
+	case int64:
+		return testIntegerObject(t, obj, expected)
+	case bool:
+		return testBooleanObject(t, obj, expected)
+	case string:
+		return testStringObject(t, obj, expected)
+	case inspected:
+		if obj == nil || obj.Inspect() != string(expected) {
+			t.Errorf("wrong result. want=%s, got=%v", expected, obj)
+			return false
+		}
+		return true
+	case errorMessage:
+		return testErrorObject(t, obj, string(expected))
+	case nil:
+		return testNullObject(t, obj)
+	default:
+		t.Fatalf("unsupported expected value %T", expected)
+		return false
+	}
+}
+
+func testStringObject(t *testing.T, o object.Object, expected string) bool {
+	result, ok := o.(*object.String)
+	if !ok {
+		t.Errorf("object is not String. got=%T (%+v)", o, o)
+		return false
+	}
+
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%q, want=%q", result.Value, expected)
+		return false
+	}
+
+	return true
+}
+
+func testErrorObject(t *testing.T, obj object.Object, expected string) bool {
+	errObj, ok := obj.(*object.Error)
+	if !ok {
+		t.Errorf("object is not Error. got=%T (%+v)", obj, obj)
+		return false
+	}
+
+	if errObj.Message != expected {
+		t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+		return false
+	}
+
+	return true
+}
+
 func testNullObject(t *testing.T, obj object.Object) bool {
 	if obj != evaluator.Null {
 		t.Errorf("object is not Null. got=%T (%#v)", obj, obj)
@@ -0,0 +1,170 @@
+package evaluator
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"git.tigh.dev/tigh-latte/monkeyscript/ast"
+	"git.tigh.dev/tigh-latte/monkeyscript/object"
+)
+
+func evalInterpolatedString(str *ast.InterpolatedString, env *object.Environment) object.Object {
+	var sb strings.Builder
+	sb.WriteString(str.Head)
+
+	for _, part := range str.Parts {
+		value := Eval(part.Value, env)
+		if isError(value) {
+			return value
+		}
+
+		if part.Format == "" {
+			sb.WriteString(value.Inspect())
+		} else {
+			formatted := formatValue(value, part.Format)
+			if isError(formatted) {
+				return withPos(formatted, str.Token.Pos)
+			}
+			sb.WriteString(formatted.(*object.String).Value)
+		}
+		sb.WriteString(part.Text)
+	}
+
+	return &object.String{Value: sb.String()}
+}
+
+// formatSpec is a parsed format spec, as written after the ':' in an
+// interpolation: `[[fill]align][0][width][.precision][verb]`, e.g. `>8.2f`.
+type formatSpec struct {
+	fill      rune
+	align     byte // '<', '>' or '^', or 0 for the value's default
+	zero      bool // pad with zeros after the sign, rather than with fill
+	width     int
+	precision int  // -1 when not given
+	verb      byte // one of "sdxXobf", or 0 when not given
+}
+
+func parseFormatSpec(spec string) (formatSpec, bool) {
+	f := formatSpec{fill: ' ', precision: -1}
+
+	if r, size := utf8.DecodeRuneInString(spec); size < len(spec) && strings.IndexByte("<>^", spec[size]) >= 0 {
+		f.fill, f.align = r, spec[size]
+		spec = spec[size+1:]
+	} else if spec != "" && strings.IndexByte("<>^", spec[0]) >= 0 {
+		f.align = spec[0]
+		spec = spec[1:]
+	}
+
+	if strings.HasPrefix(spec, "0") {
+		f.zero = true
+		spec = spec[1:]
+	}
+
+	var digits string
+	if digits, spec = leadingDigits(spec); digits != "" {
+		f.width, _ = strconv.Atoi(digits)
+	}
+
+	if strings.HasPrefix(spec, ".") {
+		if digits, spec = leadingDigits(spec[1:]); digits == "" {
+			return f, false
+		}
+		f.precision, _ = strconv.Atoi(digits)
+	}
+
+	switch {
+	case spec == "":
+	case len(spec) == 1 && strings.IndexByte("sdxXobf", spec[0]) >= 0:
+		f.verb = spec[0]
+	default:
+		return f, false
+	}
+	return f, true
+}
+
+func leadingDigits(s string) (string, string) {
+	i := 0
+	for i < len(s) && '0' <= s[i] && s[i] <= '9' {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+// formatValue formats value according to a format spec. With no verb, or the
+// verb `s`, a value is written as by Inspect. The verbs `d`, `x`, `X`, `o` and
+// `b` write an integer in base 10, 16, 8 or 2, and `f` writes any number as a
+// decimal with precision digits after the point (6 by default), rounding
+// exactly. Numbers are aligned right unless written with `s`, anything else
+// left.
+func formatValue(value object.Object, spec string) object.Object {
+	f, ok := parseFormatSpec(spec)
+	if !ok {
+		return newErrorf(object.ValueError, "invalid format spec %q", spec)
+	}
+
+	var s string
+	switch f.verb {
+	case 0, 's':
+		if f.precision >= 0 {
+			return newErrorf(object.ValueError, "invalid format spec %q: precision requires the f verb", spec)
+		}
+		s = value.Inspect()
+	case 'd', 'x', 'X', 'o', 'b':
+		n, ok := object.ToBig(value)
+		if !ok {
+			return newErrorf(object.TypeError, "cannot format %s with %q", value.Type(), spec)
+		}
+		if f.precision >= 0 {
+			return newErrorf(object.ValueError, "invalid format spec %q: precision requires the f verb", spec)
+		}
+		s = n.Text(map[byte]int{'d': 10, 'x': 16, 'X': 16, 'o': 8, 'b': 2}[f.verb])
+		if f.verb == 'X' {
+			s = strings.ToUpper(s)
+		}
+	case 'f':
+		r, ok := object.ToRat(value)
+		if !ok {
+			return newErrorf(object.TypeError, "cannot format %s with %q", value.Type(), spec)
+		}
+		if f.precision < 0 {
+			f.precision = 6
+		}
+		s = r.FloatString(f.precision)
+	}
+
+	numeric := isNumber(value) && f.verb != 's'
+	return &object.String{Value: pad(s, f, numeric)}
+}
+
+func pad(s string, f formatSpec, numeric bool) string {
+	n := f.width - utf8.RuneCountInString(s)
+	if n <= 0 {
+		return s
+	}
+
+	align := f.align
+	if align == 0 && f.zero && numeric {
+		sign, digits := "", s
+		if strings.HasPrefix(s, "-") {
+			sign, digits = "-", s[1:]
+		}
+		return sign + strings.Repeat("0", n) + digits
+	}
+	if align == 0 {
+		align = '<'
+		if numeric {
+			align = '>'
+		}
+	}
+
+	fill := string(f.fill)
+	switch align {
+	case '>':
+		return strings.Repeat(fill, n) + s
+	case '^':
+		return strings.Repeat(fill, n/2) + s + strings.Repeat(fill, n-n/2)
+	default:
+		return s + strings.Repeat(fill, n)
+	}
+}
@@ -0,0 +1,87 @@
+package evaluator
+
+import (
+	"git.tigh.dev/tigh-latte/monkeyscript/ast"
+	"git.tigh.dev/tigh-latte/monkeyscript/object"
+)
+
+// iterFunc is called once per element of an iterable with the element's key
+// (position or hash key) and value. Returning a non-nil object stops
+// the iteration and hands that object back to the caller of iterate.
+type iterFunc func(key, value object.Object) object.Object
+
+// iterate walks arrays, hashes, strings and ranges. Arrays and hashes are
+// snapshotted first, so mutating them from the loop body does not change what
+// is visited.
+func iterate(iterable object.Object, fn iterFunc) object.Object {
+	switch iterable := iterable.(type) {
+	case *object.Array:
+		elems := make([]object.Object, len(iterable.Elements))
+		copy(elems, iterable.Elements)
+		for i, elem := range elems {
+			if res := fn(&object.Integer{Value: int64(i)}, elem); res != nil {
+				return res
+			}
+		}
+	case *object.Hash:
+		for _, pair := range iterable.Pairs() {
+			if res := fn(pair.Key, pair.Value); res != nil {
+				return res
+			}
+		}
+	case *object.String:
+		for i, r := range []rune(iterable.Value) {
+			if res := fn(&object.Integer{Value: int64(i)}, &object.String{Value: string(r)}); res != nil {
+				return res
+			}
+		}
+	case *object.Range:
+		for i := int64(0); i < iterable.Len(); i++ {
+			if res := fn(&object.Integer{Value: i}, &object.Integer{Value: iterable.At(i)}); res != nil {
+				return res
+			}
+		}
+	default:
+		return newErrorf(object.TypeError, "not iterable: %s", iterable.Type())
+	}
+
+	return nil
+}
+
+func evalForStatement(fs *ast.ForStatement, env *object.Environment) object.Object {
+	iterable := Eval(fs.Iterable, env)
+	if isError(iterable) {
+		return iterable
+	}
+
+	// Hashes yield their keys when given a single loop variable; everything
+	// else yields its values.
+	_, keyed := iterable.(*object.Hash)
+
+	result := iterate(iterable, func(key, value object.Object) object.Object {
+		// Every iteration gets its own scope so closures created in the body
+		// capture that iteration's bindings.
+		loopEnv := object.NewEnvironment(env)
+		switch {
+		case len(fs.Vars) == 2:
+			loopEnv.Set(fs.Vars[0].Value, key)
+			loopEnv.Set(fs.Vars[1].Value, value)
+		case keyed:
+			loopEnv.Set(fs.Vars[0].Value, key)
+		default:
+			loopEnv.Set(fs.Vars[0].Value, value)
+		}
+
+		res := Eval(fs.Body, loopEnv)
+		switch res.(type) {
+		case *object.Break, *object.ReturnValue, *object.Error:
+			return res
+		}
+		return nil
+	})
+
+	if result == nil || result == breakSignal {
+		return Null
+	}
+	return result
+}
@@ -0,0 +1,83 @@
+package evaluator
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+
+	"git.tigh.dev/tigh-latte/monkeyscript/object"
+)
+
+// encodeJSON writes obj to bb as JSON. Hashes are written in insertion order,
+// with non-string keys written as the string of their value. visiting holds
+// the arrays and hashes being written, as JSON cannot hold one within itself.
+func encodeJSON(bb *bytes.Buffer, obj object.Object, visiting map[object.Object]bool) *object.Error {
+	switch obj.(type) {
+	case *object.Array, *object.Hash:
+		if visiting[obj] {
+			return newErrorf(object.ValueError, "cannot encode cyclic %s as JSON", obj.Type())
+		}
+		visiting[obj] = true
+		defer delete(visiting, obj)
+	}
+
+	switch obj := obj.(type) {
+	case *object.Integer:
+		bb.WriteString(strconv.FormatInt(obj.Value, 10))
+	case *object.BigInt:
+		bb.WriteString(obj.Value.String())
+	case *object.Boolean:
+		bb.WriteString(strconv.FormatBool(obj.Value))
+	case *object.Null:
+		bb.WriteString("null")
+	case *object.String:
+		writeJSONString(bb, obj.Value)
+	case *object.Array:
+		bb.WriteByte('[')
+		for i, elem := range obj.Elements {
+			if i > 0 {
+				bb.WriteByte(',')
+			}
+			if err := encodeJSON(bb, elem, visiting); err != nil {
+				return err
+			}
+		}
+		bb.WriteByte(']')
+	case *object.Range:
+		bb.WriteByte('[')
+		for i := int64(0); i < obj.Len(); i++ {
+			if i > 0 {
+				bb.WriteByte(',')
+			}
+			bb.WriteString(strconv.FormatInt(obj.At(i), 10))
+		}
+		bb.WriteByte(']')
+	case *object.Hash:
+		bb.WriteByte('{')
+		for i, pair := range obj.Pairs() {
+			if i > 0 {
+				bb.WriteByte(',')
+			}
+			if key, ok := pair.Key.(*object.String); ok {
+				writeJSONString(bb, key.Value)
+			} else {
+				writeJSONString(bb, pair.Key.Inspect())
+			}
+			bb.WriteByte(':')
+			if err := encodeJSON(bb, pair.Value, visiting); err != nil {
+				return err
+			}
+		}
+		bb.WriteByte('}')
+	default:
+		return newErrorf(object.TypeError, "cannot encode %s as JSON", obj.Type())
+	}
+
+	return nil
+}
+
+func writeJSONString(bb *bytes.Buffer, s string) {
+	// Marshalling a string cannot fail.
+	b, _ := json.Marshal(s)
+	bb.Write(b)
+}
@@ -0,0 +1,190 @@
+package evaluator
+
+import (
+	"strings"
+
+	"git.tigh.dev/tigh-latte/monkeyscript/object"
+)
+
+// methods holds, per type, the functions callable as `value.name(args)`. A
+// method receives the value it was called on as its first argument, so most
+// builtins which take a collection first double as methods.
+var methods = map[object.ObjectType]map[string]*object.Builtin{
+	object.StringType: {
+		"len":      builtins["len"],
+		"upper":    stringMethod("upper", strings.ToUpper),
+		"lower":    stringMethod("lower", strings.ToLower),
+		"trim":     stringMethod("trim", strings.TrimSpace),
+		"split":    {Fn: stringSplit},
+		"contains": {Fn: stringContains},
+	},
+	object.ArrayType: {
+		"len":     builtins["len"],
+		"first":   builtins["first"],
+		"last":    builtins["last"],
+		"rest":    builtins["rest"],
+		"push":    builtins["push"],
+		"append!": builtins["append!"],
+		"set!":    builtins["set!"],
+		"delete!": builtins["delete!"],
+		"join":    {Fn: arrayJoin},
+		"sort":    builtins["sort"],
+	},
+	object.HashType: {
+		"len":     {Fn: hashLen},
+		"has":     {Fn: hashHas},
+		"keys":    builtins["keys"],
+		"values":  builtins["values"],
+		"set!":    builtins["set!"],
+		"delete!": builtins["delete!"],
+	},
+	object.RangeType: {
+		"len": builtins["len"],
+	},
+	object.RationalType: {
+		"round": builtins["round"],
+	},
+}
+
+// RegisterMethod makes fn callable as `value.name(...)` on every value of type
+// t, replacing any method already registered under that name. fn receives the
+// value as its first argument. Methods should be registered before any
+// script is evaluated.
+func RegisterMethod(t object.ObjectType, name string, fn *object.Builtin) {
+	if methods[t] == nil {
+		methods[t] = make(map[string]*object.Builtin)
+	}
+	if fn.Name == "" {
+		fn.Name = name
+	}
+	methods[t][name] = fn
+}
+
+// lookupMethod finds the function called by `receiver.name(...)`. The method
+// registered for the receiver's type comes first, and is returned along with
+// the receiver to pass as its first argument, so a hash with a "len" or
+// "keys" key still has its len and keys methods. Failing that, a value stored
+// in a hash under name is called as is, so hashes can act as modules.
+func lookupMethod(receiver object.Object, name string) (fn, self object.Object) {
+	if method, ok := methods[receiver.Type()][name]; ok {
+		return method, receiver
+	}
+
+	if hash, ok := receiver.(*object.Hash); ok {
+		if pair, ok := hash.Get(&object.String{Value: name}); ok {
+			return pair.Value, nil
+		}
+	}
+
+	return newErrorf(object.TypeError, "unknown method %s for %s", name, receiver.Type()), nil
+}
+
+func stringMethod(name string, fn func(string) string) *object.Builtin {
+	return &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newErrorf(object.ArityError, "wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			str, ok := args[0].(*object.String)
+			if !ok {
+				return newErrorf(object.TypeError, "argument to `%s` not supported, got %s", name, args[0].Type())
+			}
+			return &object.String{Value: fn(str.Value)}
+		},
+	}
+}
+
+func stringSplit(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newErrorf(object.ArityError, "wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	str, ok := args[0].(*object.String)
+	if !ok {
+		return newErrorf(object.TypeError, "argument to `split` not supported, got %s", args[0].Type())
+	}
+	sep, ok := args[1].(*object.String)
+	if !ok {
+		return newErrorf(object.TypeError, "separator for `split` must be STRING, got %s", args[1].Type())
+	}
+
+	parts := strings.Split(str.Value, sep.Value)
+	elems := make([]object.Object, len(parts))
+	for i, part := range parts {
+		elems[i] = &object.String{Value: part}
+	}
+	return &object.Array{Elements: elems}
+}
+
+func stringContains(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newErrorf(object.ArityError, "wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	str, ok := args[0].(*object.String)
+	if !ok {
+		return newErrorf(object.TypeError, "argument to `contains` not supported, got %s", args[0].Type())
+	}
+	sub, ok := args[1].(*object.String)
+	if !ok {
+		return newErrorf(object.TypeError, "argument to `contains` must be STRING, got %s", args[1].Type())
+	}
+
+	return evalBoolean(strings.Contains(str.Value, sub.Value))
+}
+
+func arrayJoin(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newErrorf(object.ArityError, "wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	arr, ok := args[0].(*object.Array)
+	if !ok {
+		return newErrorf(object.TypeError, "argument to `join` not supported, got %s", args[0].Type())
+	}
+	sep, ok := args[1].(*object.String)
+	if !ok {
+		return newErrorf(object.TypeError, "separator for `join` must be STRING, got %s", args[1].Type())
+	}
+
+	parts := make([]string, len(arr.Elements))
+	for i, elem := range arr.Elements {
+		if str, ok := elem.(*object.String); ok {
+			parts[i] = str.Value
+		} else {
+			parts[i] = elem.Inspect()
+		}
+	}
+	return &object.String{Value: strings.Join(parts, sep.Value)}
+}
+
+func hashLen(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newErrorf(object.ArityError, "wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	hash, ok := args[0].(*object.Hash)
+	if !ok {
+		return newErrorf(object.TypeError, "argument to `len` not supported, got %s", args[0].Type())
+	}
+	return &object.Integer{Value: int64(hash.Len())}
+}
+
+func hashHas(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newErrorf(object.ArityError, "wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	hash, ok := args[0].(*object.Hash)
+	if !ok {
+		return newErrorf(object.TypeError, "argument to `has` not supported, got %s", args[0].Type())
+	}
+	key, ok := object.KeyOf(args[1])
+	if !ok {
+		return newErrorf(object.TypeError, "unusable as hash key: %s", args[1].Type())
+	}
+
+	_, ok = hash.Get(key)
+	return evalBoolean(ok)
+}
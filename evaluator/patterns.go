@@ -0,0 +1,255 @@
+package evaluator
+
+import (
+	"strings"
+
+	"git.tigh.dev/tigh-latte/monkeyscript/ast"
+	"git.tigh.dev/tigh-latte/monkeyscript/object"
+	"git.tigh.dev/tigh-latte/monkeyscript/token"
+)
+
+// patternTypes maps the type names usable in a TypePattern onto the object
+// types they match.
+var patternTypes = map[string][]object.ObjectType{
+	"integer":  {object.IntegerType, object.BigIntType},
+	"int":      {object.IntegerType, object.BigIntType},
+	"boolean":  {object.BooleanType},
+	"bool":     {object.BooleanType},
+	"string":   {object.StringType},
+	"array":    {object.ArrayType},
+	"hash":     {object.HashType},
+	"range":    {object.RangeType},
+	"rational": {object.RationalType},
+	"null":     {object.NullType},
+	"error":    {object.ErrorValueType},
+	"function": {object.FunctionType, object.BuiltinType},
+}
+
+// bindPattern destructures value according to pattern, binding each name the
+// pattern introduces in env. A nil value means the element or key the pattern
+// refers to is missing, which only a DefaultPattern accepts.
+//
+// When value does not have the shape the pattern describes, mismatch holds a
+// positioned description of why; `let` reports it while `match` moves on to
+// its next arm. err is set when evaluating part of the pattern fails.
+func bindPattern(pattern ast.Pattern, value object.Object, env *object.Environment) (mismatch *object.Error, err object.Object) {
+	if def, ok := pattern.(*ast.DefaultPattern); ok {
+		if value == nil {
+			value = Eval(def.Default, env)
+			if isError(value) {
+				return nil, value
+			}
+		}
+		pattern = def.Pattern
+	}
+
+	if value == nil {
+		return newErrorfAt(patternPos(pattern), object.PatternError, "no value to bind to %s", pattern), nil
+	}
+
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		if pattern.Value == "_" {
+			return nil, nil
+		}
+		if err := declare(env, pattern, value, false); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	case *ast.LiteralPattern:
+		return matchLiteralPattern(pattern, value, env)
+	case *ast.TypePattern:
+		types, ok := patternTypes[strings.ToLower(pattern.TypeName.Value)]
+		if !ok {
+			return nil, newErrorfAt(pattern.TypeName.Token.Pos, object.PatternError, "unknown type in pattern: %s", pattern.TypeName)
+		}
+		for _, t := range types {
+			if value.Type() == t {
+				return bindPattern(pattern.Name, value, env)
+			}
+		}
+		return newErrorfAt(pattern.Token.Pos, object.PatternError, "%s does not match pattern %s", value.Type(), pattern), nil
+	case *ast.ArrayPattern:
+		return bindArrayPattern(pattern, value, env)
+	case *ast.HashPattern:
+		return bindHashPattern(pattern, value, env)
+	default:
+		return nil, newErrorfAt(patternPos(pattern), object.RuntimeError, "unsupported pattern: %s", pattern)
+	}
+}
+
+func matchLiteralPattern(pattern *ast.LiteralPattern, value object.Object, env *object.Environment) (*object.Error, object.Object) {
+	literal := Eval(pattern.Value, env)
+	if isError(literal) {
+		return nil, literal
+	}
+
+	var matched bool
+	switch literal := literal.(type) {
+	case *object.Range:
+		matched = rangeContains(literal, value)
+	default:
+		matched = object.Equal(literal, value)
+	}
+
+	if !matched {
+		return newErrorfAt(pattern.Token.Pos, object.PatternError, "%s does not match pattern %s", value.Inspect(), pattern), nil
+	}
+	return nil, nil
+}
+
+// rangeContains reports whether value is one of the integers in rng. A
+// BigInt never is, as a range's integers all fit in int64.
+func rangeContains(rng *object.Range, value object.Object) bool {
+	integer, ok := value.(*object.Integer)
+	if !ok {
+		return false
+	}
+
+	i := integer.Value
+	var offset int64
+	switch {
+	case rng.Step > 0 && i >= rng.Start:
+		offset = i - rng.Start
+	case rng.Step < 0 && i <= rng.Start:
+		offset = rng.Start - i
+	default:
+		return false
+	}
+
+	step := rng.Step
+	if step < 0 {
+		step = -step
+	}
+
+	return offset%step == 0 && offset/step < rng.Len()
+}
+
+func bindArrayPattern(pattern *ast.ArrayPattern, value object.Object, env *object.Environment) (*object.Error, object.Object) {
+	arr, ok := value.(*object.Array)
+	if !ok {
+		return newErrorfAt(pattern.Token.Pos, object.PatternError, "cannot destructure %s with array pattern %s", value.Type(), pattern), nil
+	}
+
+	if pattern.Rest == nil && len(arr.Elements) > len(pattern.Elements) {
+		return newErrorfAt(pattern.Token.Pos, object.PatternError, "array pattern %s expects %d elements, got %d",
+			pattern, len(pattern.Elements), len(arr.Elements)), nil
+	}
+
+	for i, el := range pattern.Elements {
+		var elem object.Object
+		if i < len(arr.Elements) {
+			elem = arr.Elements[i]
+		} else if _, ok := el.(*ast.DefaultPattern); !ok {
+			return newErrorfAt(pattern.Token.Pos, object.PatternError, "array pattern %s expects at least %d elements, got %d",
+				pattern, i+1, len(arr.Elements)), nil
+		}
+
+		if mismatch, err := bindPattern(el, elem, env); mismatch != nil || err != nil {
+			return mismatch, err
+		}
+	}
+
+	if pattern.Rest != nil {
+		rest := []object.Object{}
+		if len(arr.Elements) > len(pattern.Elements) {
+			rest = append(rest, arr.Elements[len(pattern.Elements):]...)
+		}
+		if err := declare(env, pattern.Rest, &object.Array{Elements: rest}, false); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, nil
+}
+
+func bindHashPattern(pattern *ast.HashPattern, value object.Object, env *object.Environment) (*object.Error, object.Object) {
+	hash, ok := value.(*object.Hash)
+	if !ok {
+		return newErrorfAt(pattern.Token.Pos, object.PatternError, "cannot destructure %s with hash pattern %s", value.Type(), pattern), nil
+	}
+
+	used := make(map[string]bool, len(pattern.Entries))
+	for _, entry := range pattern.Entries {
+		used[entry.Key] = true
+
+		var elem object.Object
+		if pair, ok := hash.Get(&object.String{Value: entry.Key}); ok {
+			elem = pair.Value
+		} else if _, ok := entry.Pattern.(*ast.DefaultPattern); !ok {
+			return newErrorfAt(patternPos(entry.Pattern), object.PatternError, "missing key %q for hash pattern %s", entry.Key, pattern), nil
+		}
+
+		if mismatch, err := bindPattern(entry.Pattern, elem, env); mismatch != nil || err != nil {
+			return mismatch, err
+		}
+	}
+
+	if pattern.Rest != nil {
+		rest := object.NewHash()
+		for _, pair := range hash.Pairs() {
+			if key, ok := pair.Key.(*object.String); !ok || !used[key.Value] {
+				rest.Set(pair.Key.(object.Hashable), pair.Value)
+			}
+		}
+		if err := declare(env, pattern.Rest, rest, false); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, nil
+}
+
+func evalMatchExpression(me *ast.MatchExpression, env *object.Environment) object.Object {
+	subject := Eval(me.Subject, env)
+	if isError(subject) {
+		return subject
+	}
+
+	for _, arm := range me.Arms {
+		// Bindings made by an arm which then fails to match must not leak
+		// into the next arm, so each arm gets its own scope.
+		armEnv := object.NewEnvironment(env)
+
+		mismatch, err := bindPattern(arm.Pattern, subject, armEnv)
+		if err != nil {
+			return err
+		}
+		if mismatch != nil {
+			continue
+		}
+
+		if arm.Guard != nil {
+			guard := Eval(arm.Guard, armEnv)
+			if isError(guard) {
+				return guard
+			}
+			if !truthy(guard) {
+				continue
+			}
+		}
+
+		return Eval(arm.Body, armEnv)
+	}
+
+	return Null
+}
+
+func patternPos(pattern ast.Pattern) token.Position {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		return pattern.Token.Pos
+	case *ast.ArrayPattern:
+		return pattern.Token.Pos
+	case *ast.HashPattern:
+		return pattern.Token.Pos
+	case *ast.LiteralPattern:
+		return pattern.Token.Pos
+	case *ast.TypePattern:
+		return pattern.Name.Token.Pos
+	case *ast.DefaultPattern:
+		return patternPos(pattern.Pattern)
+	default:
+		return token.Position{}
+	}
+}
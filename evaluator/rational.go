@@ -0,0 +1,135 @@
+package evaluator
+
+import (
+	"math/big"
+
+	"git.tigh.dev/tigh-latte/monkeyscript/object"
+)
+
+// evalRationalInfixExpression evaluates an operator between two numbers at
+// least one of which is a Rational, or the exact division `a // b`. Rational
+// arithmetic is exact, but a whole result beyond int64 is subject to mode as
+// integer arithmetic is.
+func evalRationalInfixExpression(operator string, lObj, rObj object.Object, mode object.ArithmeticMode) object.Object {
+	left, _ := object.ToRat(lObj)
+	right, _ := object.ToRat(rObj)
+
+	res := new(big.Rat)
+	switch operator {
+	case "+":
+		res.Add(left, right)
+	case "-":
+		res.Sub(left, right)
+	case "*":
+		res.Mul(left, right)
+	case "/", "//":
+		if right.Sign() == 0 {
+			return newErrorf(object.DivisionByZero, "division by zero: %s %s 0", lObj.Inspect(), operator)
+		}
+		res.Quo(left, right)
+	case "<":
+		return evalBoolean(left.Cmp(right) < 0)
+	case ">":
+		return evalBoolean(left.Cmp(right) > 0)
+	case "==":
+		return evalBoolean(left.Cmp(right) == 0)
+	case "!=":
+		return evalBoolean(left.Cmp(right) != 0)
+	default:
+		return newErrorf(object.TypeError, "unknown operator: %s %s %s", lObj.Type(), operator, rObj.Type())
+	}
+
+	if !res.IsInt() {
+		return &object.Rational{Value: res}
+	}
+	n, ok := integerInMode(res.Num(), mode)
+	if !ok {
+		return newErrorf(object.OverflowError, "integer overflow: %s %s %s", lObj.Inspect(), operator, rObj.Inspect())
+	}
+	return n
+}
+
+// isNumber reports whether obj is an Integer, BigInt or Rational, the number
+// types which rational arithmetic and comparison mix freely.
+func isNumber(obj object.Object) bool {
+	switch obj.(type) {
+	case *object.Integer, *object.BigInt, *object.Rational:
+		return true
+	}
+	return false
+}
+
+// numberRatio is `ratio(a, b)`, the exact quotient of a and b.
+func numberRatio(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newErrorf(object.ArityError, "wrong number of arguments. got=%d, want=2", len(args))
+	}
+	for _, arg := range args {
+		if !isNumber(arg) {
+			return newErrorf(object.TypeError, "argument to `ratio` not supported, got %s", arg.Type())
+		}
+	}
+
+	// A whole quotient beyond int64 is brought into the arithmetic mode
+	// by applyFunction.
+	return evalRationalInfixExpression("//", args[0], args[1], object.BigArithmetic)
+}
+
+// roundingModes maps the modes accepted by `round` onto functions rounding
+// the truncated quotient q of a fraction, given the sign of the fraction and
+// how its remainder compares to one half.
+var roundingModes = map[string]func(q *big.Int, sign, half int) bool{
+	"down":      func(q *big.Int, sign, half int) bool { return false },
+	"up":        func(q *big.Int, sign, half int) bool { return true },
+	"floor":     func(q *big.Int, sign, half int) bool { return sign < 0 },
+	"ceil":      func(q *big.Int, sign, half int) bool { return sign > 0 },
+	"half_down": func(q *big.Int, sign, half int) bool { return half > 0 },
+	"half_up":   func(q *big.Int, sign, half int) bool { return half >= 0 },
+	"half_even": func(q *big.Int, sign, half int) bool { return half > 0 || half == 0 && q.Bit(0) == 1 },
+}
+
+// numberRound is `round(x, mode: "half_even")`, which converts a number to
+// the nearest integer. mode is one of the roundingModes, and may also be
+// passed positionally; it defaults to "half_even".
+func numberRound(kwargs object.Keywords, args ...object.Object) object.Object {
+	if len(args) < 1 || len(args) > 2 || len(args)+len(kwargs) > 2 {
+		return newErrorf(object.ArityError, "wrong number of arguments. got=%d, want=1..2", len(args)+len(kwargs))
+	}
+
+	mode := object.Object(&object.String{Value: "half_even"})
+	if len(args) == 2 {
+		mode = args[1]
+	}
+	for _, kw := range kwargs {
+		if kw.Name != "mode" {
+			return newErrorf(object.ArityError, "unknown keyword argument %s to `round`", kw.Name)
+		}
+		mode = kw.Value
+	}
+
+	name, ok := mode.(*object.String)
+	if !ok {
+		return newErrorf(object.TypeError, "rounding mode must be STRING, got %s", mode.Type())
+	}
+	roundAway, ok := roundingModes[name.Value]
+	if !ok {
+		return newErrorf(object.ValueError, "unknown rounding mode: %s", name.Value)
+	}
+
+	x, ok := object.ToRat(args[0])
+	if !ok {
+		return newErrorf(object.TypeError, "argument to `round` not supported, got %s", args[0].Type())
+	}
+
+	q, r := new(big.Int).QuoRem(x.Num(), x.Denom(), new(big.Int))
+	if r.Sign() == 0 {
+		return object.NewInteger(q)
+	}
+
+	// Compare the remainder against one half: |2r| against the denominator.
+	half := new(big.Int).Abs(r.Lsh(r, 1)).Cmp(x.Denom())
+	if roundAway(q, x.Sign(), half) {
+		q.Add(q, big.NewInt(int64(x.Sign())))
+	}
+	return object.NewInteger(q)
+}
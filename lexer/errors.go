@@ -0,0 +1,5 @@
+package lexer
+
+import "errors"
+
+var ErrMalformedNumber = errors.New("malformed number literal")
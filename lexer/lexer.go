@@ -1,6 +1,11 @@
 package lexer
 
 import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
 	"git.tigh.dev/tigh-latte/monkeyscript/token"
 )
 
@@ -16,21 +21,37 @@ type Lexer struct {
 
 	// current char under examination
 	ch byte
+
+	// line and column of the current char
+	line   int
+	column int
+
+	// err is the problem found lexing the most recent token, if any.
+	err error
+
+	// interps holds, for each interpolation being lexed, the bracket depth
+	// of the one enclosing it; depth is the bracket depth within the
+	// innermost. A `}` or `:` at depth 0 ends the interpolation's
+	// expression rather than belonging to it.
+	interps []int
+	depth   int
 }
 
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	l := &Lexer{input: input, line: 1}
 	l.readChar()
 	return l
 }
 
 func (l *Lexer) NextToken() token.Token {
 	var tok token.Token
+	l.err = nil
 
 	// Skip whitespace.
 	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
 		l.readChar()
 	}
+	pos := token.Position{Line: l.line, Column: l.column}
 
 	switch l.ch {
 	case '=':
@@ -39,13 +60,56 @@ func (l *Lexer) NextToken() token.Token {
 			l.readChar()
 			literal := string(ch) + string(l.ch)
 			tok = token.Token{Type: token.EQ, Literal: literal}
+		} else if l.peakChar() == '>' {
+			l.readChar()
+			tok = token.Token{Type: token.FATARROW, Literal: "=>"}
 		} else {
 			tok = token.Token{Type: token.ASSIGN, Literal: string(l.ch)}
 		}
 	case ';':
 		tok = token.Token{Type: token.SEMICOLON, Literal: string(l.ch)}
+	case '.':
+		if l.peakChar() == '.' {
+			l.readChar()
+			tok = token.Token{Type: token.DOTDOT, Literal: ".."}
+			switch l.peakChar() {
+			case '<':
+				l.readChar()
+				tok = token.Token{Type: token.DOTDOTLT, Literal: "..<"}
+			case '.':
+				l.readChar()
+				tok = token.Token{Type: token.ELLIPSIS, Literal: "..."}
+			}
+		} else {
+			tok = token.Token{Type: token.DOT, Literal: string(l.ch)}
+		}
 	case ':':
+		if len(l.interps) > 0 && l.depth == 0 {
+			tok = token.Token{Type: token.FORMATSPEC, Literal: l.readFormatSpec(), Pos: pos}
+			return tok
+		}
 		tok = token.Token{Type: token.COLON, Literal: string(l.ch)}
+	case '?':
+		switch l.peakChar() {
+		case '.':
+			l.readChar()
+			tok = token.Token{Type: token.OPTDOT, Literal: "?."}
+		case '[':
+			l.readChar()
+			tok = token.Token{Type: token.OPTLSQUAR, Literal: "?["}
+		case '?':
+			l.readChar()
+			tok = token.Token{Type: token.COALESCE, Literal: "??"}
+		default:
+			tok = token.Token{Type: token.ILLEGAL, Literal: string(l.ch)}
+		}
+	case '|':
+		if l.peakChar() == '>' {
+			l.readChar()
+			tok = token.Token{Type: token.PIPELINE, Literal: "|>"}
+		} else {
+			tok = token.Token{Type: token.PIPE, Literal: string(l.ch)}
+		}
 	case '(':
 		tok = token.Token{Type: token.LPAREN, Literal: string(l.ch)}
 	case ')':
@@ -59,7 +123,12 @@ func (l *Lexer) NextToken() token.Token {
 	case '*':
 		tok = token.Token{Type: token.ASTERISK, Literal: string(l.ch)}
 	case '/':
-		tok = token.Token{Type: token.SLASH, Literal: string(l.ch)}
+		if l.peakChar() == '/' {
+			l.readChar()
+			tok = token.Token{Type: token.RATIO, Literal: "//"}
+		} else {
+			tok = token.Token{Type: token.SLASH, Literal: string(l.ch)}
+		}
 	case '<':
 		tok = token.Token{Type: token.LT, Literal: string(l.ch)}
 	case '>':
@@ -76,6 +145,12 @@ func (l *Lexer) NextToken() token.Token {
 	case '{':
 		tok = token.Token{Type: token.LSQUIG, Literal: string(l.ch)}
 	case '}':
+		if len(l.interps) > 0 && l.depth == 0 {
+			l.depth = l.interps[len(l.interps)-1]
+			l.interps = l.interps[:len(l.interps)-1]
+			tok = l.readStringPart(token.INTERPEND, token.INTERPMID)
+			break
+		}
 		tok = token.Token{Type: token.RSQUIG, Literal: string(l.ch)}
 	case '[':
 		tok = token.Token{Type: token.LSQUAR, Literal: string(l.ch)}
@@ -85,27 +160,51 @@ func (l *Lexer) NextToken() token.Token {
 		tok.Literal = ""
 		tok.Type = token.EOF
 	case '"':
-		tok.Type = token.STRING
-		tok.Literal = l.readString()
+		tok = l.readStringPart(token.STRING, token.INTERPSTART)
 	default:
 		if isLetter(l.ch) {
-			tok.Literal = l.readSymbol(isLetter)
+			tok.Literal = l.readIdentifier()
 			tok.Type = token.LookupIdent(tok.Literal)
+			tok.Pos = pos
 			return tok
 		} else if isDigit(l.ch) {
 			tok.Type = token.INT
-			tok.Literal = l.readSymbol(isDigit)
+			tok.Literal = l.readNumber(pos)
+			tok.Pos = pos
 			return tok
 		} else {
 			tok = token.Token{Type: token.ILLEGAL, Literal: string(l.ch)}
 		}
 	}
 
+	if len(l.interps) > 0 {
+		switch tok.Type {
+		case token.LPAREN, token.LSQUAR, token.LSQUIG, token.OPTLSQUAR:
+			l.depth++
+		case token.RPAREN, token.RSQUAR, token.RSQUIG:
+			l.depth--
+		}
+	}
+
 	l.readChar()
+	tok.Pos = pos
 	return tok
 }
 
+// Err reports the problem found lexing the token most recently returned by
+// NextToken, such as a malformed number literal. The token is still returned
+// as written so parsing can carry on past it.
+func (l *Lexer) Err() error {
+	return l.err
+}
+
 func (l *Lexer) readChar() {
+	if l.ch == '\n' {
+		l.line++
+		l.column = 0
+	}
+	l.column++
+
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
 	} else {
@@ -123,25 +222,144 @@ func (l *Lexer) peakChar() byte {
 	return l.input[l.readPosition]
 }
 
-func (l *Lexer) readSymbol(truth func(byte) bool) string {
+// numberBases maps the prefixes of non-decimal integer literals onto the name
+// of their base and the digits it allows.
+var numberBases = map[string]struct {
+	name    string
+	isDigit func(byte) bool
+}{
+	"0x": {"hexadecimal", isHexDigit},
+	"0b": {"binary", isBinaryDigit},
+	"0o": {"octal", isOctalDigit},
+}
+
+// readNumber reads an integer literal: decimal, or hexadecimal, binary or
+// octal with a `0x`, `0b` or `0o` prefix. Digits may be grouped with single
+// underscores, e.g. `1_000_000` or `0xFF_FF`. A decimal literal may have an
+// exponent, e.g. `1e3` or `25e-2`. Any letters or digits run
+// together with the literal are read as part of it, so `0xFG` is reported as
+// malformed rather than lexed as `0xF` followed by `G`.
+func (l *Lexer) readNumber(pos token.Position) string {
+	position := l.position
+	hex := l.ch == '0' && (l.peakChar() == 'x' || l.peakChar() == 'X')
+	for isLetter(l.ch) || isDigit(l.ch) {
+		// Read a signed exponent whole, so it is reported as one literal.
+		if !hex && (l.ch == 'e' || l.ch == 'E') && (l.peakChar() == '+' || l.peakChar() == '-') {
+			l.readChar()
+		}
+		l.readChar()
+	}
+	literal := l.input[position:l.position]
+
+	if reason := checkNumber(literal); reason != "" {
+		l.err = fmt.Errorf("%s: %w %q: %s", pos, ErrMalformedNumber, literal, reason)
+	}
+	return literal
+}
+
+// maxExponent bounds the exponent of a number literal, so that `1e999999999`
+// is rejected rather than expanded into a billion digit integer.
+const maxExponent = 10_000
+
+// checkNumber describes what is wrong with an integer literal, or returns ""
+// if it is well formed.
+func checkNumber(literal string) string {
+	if len(literal) > 2 {
+		if base, ok := numberBases[strings.ToLower(literal[:2])]; ok {
+			return checkDigits(literal[2:], base.name, base.isDigit)
+		}
+	} else if base, ok := numberBases[strings.ToLower(literal)]; ok {
+		return "missing " + base.name + " digits"
+	}
+
+	i := strings.IndexAny(literal, "eE")
+	if i < 0 {
+		return checkDigits(literal, "decimal", isDigit)
+	}
+	if reason := checkDigits(literal[:i], "decimal", isDigit); reason != "" {
+		return reason
+	}
+	exponent := literal[i+1:]
+	if exponent != "" && (exponent[0] == '+' || exponent[0] == '-') {
+		exponent = exponent[1:]
+	}
+	if exponent == "" {
+		return "missing exponent digits"
+	}
+	if reason := checkDigits(exponent, "exponent", isDigit); reason != "" {
+		return reason
+	}
+	if n, err := strconv.Atoi(strings.ReplaceAll(exponent, "_", "")); err != nil || n > maxExponent {
+		return fmt.Sprintf("exponent exceeds %d", maxExponent)
+	}
+	return ""
+}
+
+// checkDigits describes what is wrong with the digits of a literal in the
+// named base, which may be grouped with single underscores.
+func checkDigits(digits, name string, isDigitOf func(byte) bool) string {
+	for i := 0; i < len(digits); i++ {
+		switch ch := digits[i]; {
+		case ch == '_':
+			if i == 0 || i == len(digits)-1 || digits[i+1] == '_' {
+				return "'_' must separate digits"
+			}
+		case isDigitOf(ch):
+		default:
+			return fmt.Sprintf("invalid digit %q in %s literal", ch, name)
+		}
+	}
+	return ""
+}
+
+// readIdentifier reads an identifier, allowing a single trailing '!' to mark
+// functions which mutate their arguments, e.g. `append!`. A '!' followed by
+// '=' is left alone so `a!=b` still lexes as a comparison.
+func (l *Lexer) readIdentifier() string {
 	position := l.position
-	for truth(l.ch) {
+	for isLetter(l.ch) {
+		l.readChar()
+	}
+	if l.ch == '!' && l.peakChar() != '=' {
 		l.readChar()
 	}
 
 	return l.input[position:l.position]
 }
 
-func (l *Lexer) readString() string {
+// readStringPart reads the text following the current char up to the closing
+// '"', giving a token of type end, or up to a `${` opening an interpolation,
+// giving a token of type open.
+func (l *Lexer) readStringPart(end, open token.TokenType) token.Token {
 	position := l.position + 1
 	for {
 		l.readChar()
 		if l.ch == '"' || l.ch == 0 {
-			break
+			return token.Token{Type: end, Literal: l.input[position:l.position]}
+		}
+		if l.ch == '$' && l.peakChar() == '{' {
+			tok := token.Token{Type: open, Literal: l.input[position:l.position]}
+			l.readChar()
+			// The parser looks ahead on copies of the lexer, so the
+			// stack is never appended to in place where a copy could
+			// overwrite the original's entries.
+			l.interps = append(slices.Clip(l.interps), l.depth)
+			l.depth = 0
+			return tok
 		}
 	}
+}
 
-	return l.input[position:l.position]
+// readFormatSpec reads the format spec following the ':' in an interpolation,
+// leaving the closing '}' to be read next.
+func (l *Lexer) readFormatSpec() string {
+	position := l.position + 1
+	for {
+		l.readChar()
+		if l.ch == '}' || l.ch == '"' || l.ch == 0 {
+			return l.input[position:l.position]
+		}
+	}
 }
 
 func isLetter(ch byte) bool {
@@ -153,3 +371,15 @@ func isLetter(ch byte) bool {
 func isDigit(ch byte) bool {
 	return '0' <= ch && ch <= '9'
 }
+
+func isHexDigit(ch byte) bool {
+	return isDigit(ch) || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
+}
+
+func isBinaryDigit(ch byte) bool {
+	return ch == '0' || ch == '1'
+}
+
+func isOctalDigit(ch byte) bool {
+	return '0' <= ch && ch <= '7'
+}
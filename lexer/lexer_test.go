@@ -1,6 +1,7 @@
 package lexer_test
 
 import (
+	"errors"
 	"testing"
 
 	"git.tigh.dev/tigh-latte/monkeyscript/lexer"
@@ -32,6 +33,19 @@ func TestNextToken(t *testing.T) {
 	"foo bar"
 	[1, 2];
 	{"name": "Jimmy", "age": 72, "band": "Led Zeppelin"};
+	append!(a, 1);
+	a!=b;
+	while (x) { break; continue; }
+	for (k, v in 0..n) {}
+	0..<n
+	f(...args)
+	match (x) { _ => 1 }
+	xs |> map(|x| x)
+	txn.amount
+	a?.b?[0] ?? null
+	const x = 1;
+	try {} catch (e) {} finally {} throw
+	7 // 30 / 2
 	`
 
 	tests := []struct {
@@ -155,6 +169,125 @@ func TestNextToken(t *testing.T) {
 		{token.STRING, "Led Zeppelin"},
 		{token.RSQUIG, "}"},
 		{token.SEMICOLON, ";"},
+
+		// append!(a, 1);
+		{token.IDENT, "append!"},
+		{token.LPAREN, "("},
+		{token.IDENT, "a"},
+		{token.COMMA, ","},
+		{token.INT, "1"},
+		{token.RPAREN, ")"},
+		{token.SEMICOLON, ";"},
+
+		// a!=b;
+		{token.IDENT, "a"},
+		{token.NEQ, "!="},
+		{token.IDENT, "b"},
+		{token.SEMICOLON, ";"},
+
+		// while (x) { break; continue; }
+		{token.WHILE, "while"},
+		{token.LPAREN, "("},
+		{token.IDENT, "x"},
+		{token.RPAREN, ")"},
+		{token.LSQUIG, "{"},
+		{token.BREAK, "break"},
+		{token.SEMICOLON, ";"},
+		{token.CONTINUE, "continue"},
+		{token.SEMICOLON, ";"},
+		{token.RSQUIG, "}"},
+
+		// for (k, v in 0..n) {}
+		{token.FOR, "for"},
+		{token.LPAREN, "("},
+		{token.IDENT, "k"},
+		{token.COMMA, ","},
+		{token.IDENT, "v"},
+		{token.IN, "in"},
+		{token.INT, "0"},
+		{token.DOTDOT, ".."},
+		{token.IDENT, "n"},
+		{token.RPAREN, ")"},
+		{token.LSQUIG, "{"},
+		{token.RSQUIG, "}"},
+
+		// 0..<n
+		{token.INT, "0"},
+		{token.DOTDOTLT, "..<"},
+		{token.IDENT, "n"},
+
+		// f(...args)
+		{token.IDENT, "f"},
+		{token.LPAREN, "("},
+		{token.ELLIPSIS, "..."},
+		{token.IDENT, "args"},
+		{token.RPAREN, ")"},
+
+		// match (x) { _ => 1 }
+		{token.MATCH, "match"},
+		{token.LPAREN, "("},
+		{token.IDENT, "x"},
+		{token.RPAREN, ")"},
+		{token.LSQUIG, "{"},
+		{token.IDENT, "_"},
+		{token.FATARROW, "=>"},
+		{token.INT, "1"},
+		{token.RSQUIG, "}"},
+
+		// xs |> map(|x| x)
+		{token.IDENT, "xs"},
+		{token.PIPELINE, "|>"},
+		{token.IDENT, "map"},
+		{token.LPAREN, "("},
+		{token.PIPE, "|"},
+		{token.IDENT, "x"},
+		{token.PIPE, "|"},
+		{token.IDENT, "x"},
+		{token.RPAREN, ")"},
+
+		// txn.amount
+		{token.IDENT, "txn"},
+		{token.DOT, "."},
+		{token.IDENT, "amount"},
+
+		// a?.b?[0] ?? null
+		{token.IDENT, "a"},
+		{token.OPTDOT, "?."},
+		{token.IDENT, "b"},
+		{token.OPTLSQUAR, "?["},
+		{token.INT, "0"},
+		{token.RSQUAR, "]"},
+		{token.COALESCE, "??"},
+		{token.NULL, "null"},
+
+		// const x = 1;
+		{token.CONST, "const"},
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.INT, "1"},
+		{token.SEMICOLON, ";"},
+
+		// try {} catch (e) {} finally {} throw
+		{token.TRY, "try"},
+		{token.LSQUIG, "{"},
+		{token.RSQUIG, "}"},
+		{token.CATCH, "catch"},
+		{token.LPAREN, "("},
+		{token.IDENT, "e"},
+		{token.RPAREN, ")"},
+		{token.LSQUIG, "{"},
+		{token.RSQUIG, "}"},
+		{token.FINALLY, "finally"},
+		{token.LSQUIG, "{"},
+		{token.RSQUIG, "}"},
+		{token.THROW, "throw"},
+
+		// 7 // 30 / 2
+		{token.INT, "7"},
+		{token.RATIO, "//"},
+		{token.INT, "30"},
+		{token.SLASH, "/"},
+		{token.INT, "2"},
 		{token.EOF, ""},
 	}
 
@@ -172,3 +305,146 @@ func TestNextToken(t *testing.T) {
 		}
 	}
 }
+
+func TestInterpolatedStringTokens(t *testing.T) {
+	input := `"Total: ${sum(xs[0], {"a": 1}):>8.2f} for ${c.name}!" "${"in${n}"}" "$5"`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.INTERPSTART, "Total: "},
+		{token.IDENT, "sum"},
+		{token.LPAREN, "("},
+		{token.IDENT, "xs"},
+		{token.LSQUAR, "["},
+		{token.INT, "0"},
+		{token.RSQUAR, "]"},
+		{token.COMMA, ","},
+		{token.LSQUIG, "{"},
+		{token.STRING, "a"},
+		{token.COLON, ":"},
+		{token.INT, "1"},
+		{token.RSQUIG, "}"},
+		{token.RPAREN, ")"},
+		{token.FORMATSPEC, ">8.2f"},
+		{token.INTERPMID, " for "},
+		{token.IDENT, "c"},
+		{token.DOT, "."},
+		{token.IDENT, "name"},
+		{token.INTERPEND, "!"},
+
+		{token.INTERPSTART, ""},
+		{token.INTERPSTART, "in"},
+		{token.IDENT, "n"},
+		{token.INTERPEND, ""},
+		{token.INTERPEND, ""},
+
+		{token.STRING, "$5"},
+		{token.EOF, ""},
+	}
+
+	l := lexer.New(input)
+
+	for i, test := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != test.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, test.expectedType, tok.Type)
+		}
+
+		if tok.Literal != test.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, test.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestNumberLiterals(t *testing.T) {
+	tests := []struct {
+		input   string
+		literal string
+		err     string
+	}{
+		{"1_000_000", "1_000_000", ""},
+		{"0xFF_ff", "0xFF_ff", ""},
+		{"0XfF", "0XfF", ""},
+		{"0b1010", "0b1010", ""},
+		{"0o17", "0o17", ""},
+		{"007", "007", ""},
+		{"0xe+1", "0xe", ""},
+		{"0x", "0x", `1:1: malformed number literal "0x": missing hexadecimal digits`},
+		{"0b102", "0b102", `1:1: malformed number literal "0b102": invalid digit '2' in binary literal`},
+		{"0o8", "0o8", `1:1: malformed number literal "0o8": invalid digit '8' in octal literal`},
+		{"0xFG", "0xFG", `1:1: malformed number literal "0xFG": invalid digit 'G' in hexadecimal literal`},
+		{"12abc", "12abc", `1:1: malformed number literal "12abc": invalid digit 'a' in decimal literal`},
+		{"1__0", "1__0", `1:1: malformed number literal "1__0": '_' must separate digits`},
+		{"1_", "1_", `1:1: malformed number literal "1_": '_' must separate digits`},
+		{"0x_1", "0x_1", `1:1: malformed number literal "0x_1": '_' must separate digits`},
+		{"1e9", "1e9", ""},
+		{"x = 5E-3", "5E-3", ""},
+		{"1_000e+1_0", "1_000e+1_0", ""},
+		{"1e", "1e", `1:1: malformed number literal "1e": missing exponent digits`},
+		{"1e+", "1e+", `1:1: malformed number literal "1e+": missing exponent digits`},
+		{"1e3x", "1e3x", `1:1: malformed number literal "1e3x": invalid digit 'x' in exponent literal`},
+		{"1_e3", "1_e3", `1:1: malformed number literal "1_e3": '_' must separate digits`},
+		{"1e99999", "1e99999", `1:1: malformed number literal "1e99999": exponent exceeds 10000`},
+	}
+
+	for _, test := range tests {
+		l := lexer.New(test.input)
+		tok := l.NextToken()
+		for tok.Type != token.INT && tok.Type != token.EOF {
+			tok = l.NextToken()
+		}
+
+		if tok.Literal != test.literal {
+			t.Errorf("%q - literal wrong. expected=%q, got=%q", test.input, test.literal, tok.Literal)
+		}
+
+		var got string
+		if err := l.Err(); err != nil {
+			if !errors.Is(err, lexer.ErrMalformedNumber) {
+				t.Errorf("%q - error is not ErrMalformedNumber. got=%v", test.input, err)
+			}
+			got = err.Error()
+		}
+		if got != test.err {
+			t.Errorf("%q - error wrong. expected=%q, got=%q", test.input, test.err, got)
+		}
+	}
+}
+
+func TestTokenPositions(t *testing.T) {
+	input := "let x = 5;\n  x + \"a\nb\";\nfoo"
+
+	tests := []struct {
+		expectedType token.TokenType
+		expectedPos  token.Position
+	}{
+		{token.LET, token.Position{Line: 1, Column: 1}},
+		{token.IDENT, token.Position{Line: 1, Column: 5}},
+		{token.ASSIGN, token.Position{Line: 1, Column: 7}},
+		{token.INT, token.Position{Line: 1, Column: 9}},
+		{token.SEMICOLON, token.Position{Line: 1, Column: 10}},
+		{token.IDENT, token.Position{Line: 2, Column: 3}},
+		{token.PLUS, token.Position{Line: 2, Column: 5}},
+		{token.STRING, token.Position{Line: 2, Column: 7}},
+		{token.SEMICOLON, token.Position{Line: 3, Column: 3}},
+		{token.IDENT, token.Position{Line: 4, Column: 1}},
+		{token.EOF, token.Position{Line: 4, Column: 4}},
+	}
+
+	l := lexer.New(input)
+
+	for i, test := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != test.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, test.expectedType, tok.Type)
+		}
+
+		if tok.Pos != test.expectedPos {
+			t.Fatalf("tests[%d] - position wrong. expected=%s, got=%s", i, test.expectedPos, tok.Pos)
+		}
+	}
+}
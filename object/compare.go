@@ -0,0 +1,306 @@
+package object
+
+import (
+	"cmp"
+	"math/big"
+	"slices"
+	"strings"
+)
+
+// Equaler is implemented by objects which are equal by value rather than by
+// identity. Equal is only called with an object of the same Type.
+type Equaler interface {
+	Equal(other Object) bool
+}
+
+// Comparer is implemented by objects with a natural order. Compare is only
+// called with an object of the same Type, and returns a negative number, zero
+// or a positive number as the object sorts before, with or after other.
+type Comparer interface {
+	Compare(other Object) int
+}
+
+// visiting records the arrays and hashes an operation is part way through,
+// so that a value which contains itself, as `append!(a, a)` makes, is noticed
+// rather than recursed into without end. Operations on one value record it
+// paired with nil.
+type visiting struct {
+	m map[[2]Object]bool
+}
+
+// enter records that the operation is inside a and b, reporting false if it
+// already was.
+func (v *visiting) enter(a, b Object) bool {
+	if v.m[[2]Object{a, b}] {
+		return false
+	}
+	if v.m == nil {
+		v.m = make(map[[2]Object]bool)
+	}
+	v.m[[2]Object{a, b}] = true
+	return true
+}
+
+func (v *visiting) leave(a, b Object) {
+	delete(v.m, [2]Object{a, b})
+}
+
+// Equal reports whether a and b are equal. Objects of different types are
+// never equal, and objects which do not implement Equaler, such as functions,
+// are only equal to themselves. Cyclic arrays and hashes are equal when no
+// difference can be found by following them.
+func Equal(a, b Object) bool {
+	return equal(a, b, &visiting{})
+}
+
+func equal(a, b Object, v *visiting) bool {
+	if a == b {
+		return true
+	}
+	if a.Type() != b.Type() {
+		return false
+	}
+
+	switch a := a.(type) {
+	case *Array:
+		if !v.enter(a, b) {
+			return true
+		}
+		defer v.leave(a, b)
+		return slices.EqualFunc(a.Elements, b.(*Array).Elements, func(x, y Object) bool { return equal(x, y, v) })
+	case *Hash:
+		if !v.enter(a, b) {
+			return true
+		}
+		defer v.leave(a, b)
+		return a.equal(b.(*Hash), v)
+	}
+
+	if eq, ok := a.(Equaler); ok {
+		return eq.Equal(b)
+	}
+	return false
+}
+
+// typeOrder ranks the built-in types for Compare. Other types sort after
+// them, by type name.
+var typeOrder = map[ObjectType]int{
+	NullType:     1,
+	BooleanType:  2,
+	IntegerType:  3,
+	BigIntType:   3,
+	RationalType: 3,
+	StringType:   4,
+	ArrayType:    5,
+	HashType:     6,
+	RangeType:    7,
+}
+
+// Compare is a total order over all objects, suitable for sorting. Objects of
+// different types are ordered by type, and objects of the same type by their
+// Comparer or, failing that, by their Inspect output. Integers, BigInts and
+// Rationals are ordered together, by value.
+func Compare(a, b Object) int {
+	return compare(a, b, &visiting{})
+}
+
+func compare(a, b Object, v *visiting) int {
+	if a == b {
+		return 0
+	}
+	if a.Type() != b.Type() {
+		if x, ok := ToRat(a); ok {
+			if y, ok := ToRat(b); ok {
+				return x.Cmp(y)
+			}
+		}
+		ra, oka := typeOrder[a.Type()]
+		rb, okb := typeOrder[b.Type()]
+		switch {
+		case oka && okb:
+			return cmp.Compare(ra, rb)
+		case oka:
+			return -1
+		case okb:
+			return 1
+		default:
+			return strings.Compare(string(a.Type()), string(b.Type()))
+		}
+	}
+
+	switch a := a.(type) {
+	case *Array:
+		if !v.enter(a, b) {
+			return 0
+		}
+		defer v.leave(a, b)
+		return slices.CompareFunc(a.Elements, b.(*Array).Elements, func(x, y Object) int { return compare(x, y, v) })
+	case *Hash:
+		if !v.enter(a, b) {
+			return 0
+		}
+		defer v.leave(a, b)
+		return a.compare(b.(*Hash), v)
+	}
+
+	if c, ok := a.(Comparer); ok {
+		return c.Compare(b)
+	}
+	return strings.Compare(a.Inspect(), b.Inspect())
+}
+
+func (i *Integer) Equal(other Object) bool {
+	return i.Value == other.(*Integer).Value
+}
+
+func (i *Integer) Compare(other Object) int {
+	return cmp.Compare(i.Value, other.(*Integer).Value)
+}
+
+func (b *BigInt) Equal(other Object) bool {
+	return b.Value.Cmp(other.(*BigInt).Value) == 0
+}
+
+func (b *BigInt) Compare(other Object) int {
+	return b.Value.Cmp(other.(*BigInt).Value)
+}
+
+func (r *Rational) Equal(other Object) bool {
+	return r.Value.Cmp(other.(*Rational).Value) == 0
+}
+
+func (r *Rational) Compare(other Object) int {
+	return r.Value.Cmp(other.(*Rational).Value)
+}
+
+// ToBig returns the value of an Integer or BigInt as a big.Int, which must not
+// be modified.
+func ToBig(obj Object) (*big.Int, bool) {
+	switch obj := obj.(type) {
+	case *Integer:
+		return big.NewInt(obj.Value), true
+	case *BigInt:
+		return obj.Value, true
+	}
+	return nil, false
+}
+
+// ToRat returns the value of an Integer, BigInt or Rational as a big.Rat,
+// which must not be modified.
+func ToRat(obj Object) (*big.Rat, bool) {
+	switch obj := obj.(type) {
+	case *Integer:
+		return new(big.Rat).SetInt64(obj.Value), true
+	case *BigInt:
+		return new(big.Rat).SetInt(obj.Value), true
+	case *Rational:
+		return obj.Value, true
+	}
+	return nil, false
+}
+
+func (s *String) Equal(other Object) bool {
+	return s.Value == other.(*String).Value
+}
+
+// Compare orders strings bytewise.
+func (s *String) Compare(other Object) int {
+	return strings.Compare(s.Value, other.(*String).Value)
+}
+
+func (b *Boolean) Equal(other Object) bool {
+	return b.Value == other.(*Boolean).Value
+}
+
+// Compare orders false before true.
+func (b *Boolean) Compare(other Object) int {
+	switch o := other.(*Boolean); {
+	case b.Value == o.Value:
+		return 0
+	case b.Value:
+		return 1
+	default:
+		return -1
+	}
+}
+
+func (null *Null) Equal(other Object) bool { return true }
+
+func (null *Null) Compare(other Object) int { return 0 }
+
+// Equal reports whether both arrays hold equal elements in the same order.
+func (a *Array) Equal(other Object) bool {
+	return Equal(a, other)
+}
+
+// Compare orders arrays lexicographically.
+func (a *Array) Compare(other Object) int {
+	return Compare(a, other)
+}
+
+// Equal reports whether both hashes hold equal values under the same keys,
+// regardless of the order the keys were set in.
+func (h *Hash) Equal(other Object) bool {
+	return Equal(h, other)
+}
+
+func (h *Hash) equal(o *Hash, v *visiting) bool {
+	if h.Len() != o.Len() {
+		return false
+	}
+
+	for _, pair := range h.order {
+		opair, ok := o.Get(pair.Key.(Hashable))
+		if !ok || !equal(pair.Value, opair.Value, v) {
+			return false
+		}
+	}
+	return true
+}
+
+// Compare orders hashes by their pairs sorted by key, so that equal hashes
+// compare as equal whatever order their keys were set in.
+func (h *Hash) Compare(other Object) int {
+	return Compare(h, other)
+}
+
+func (h *Hash) compare(o *Hash, v *visiting) int {
+	return slices.CompareFunc(h.sortedPairs(), o.sortedPairs(), func(a, b HashPair) int {
+		if c := Compare(a.Key, b.Key); c != 0 {
+			return c
+		}
+		return compare(a.Value, b.Value, v)
+	})
+}
+
+func (h *Hash) sortedPairs() []HashPair {
+	pairs := h.Pairs()
+	slices.SortFunc(pairs, func(a, b HashPair) int { return Compare(a.Key, b.Key) })
+	return pairs
+}
+
+func (r *Range) Equal(other Object) bool {
+	return *r == *other.(*Range)
+}
+
+// Compare orders ranges by start, then end, then step.
+func (r *Range) Compare(other Object) int {
+	o := other.(*Range)
+	if c := cmp.Compare(r.Start, o.Start); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(r.End, o.End); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(r.Step, o.Step); c != 0 {
+		return c
+	}
+	switch {
+	case r.Exclusive == o.Exclusive:
+		return 0
+	case r.Exclusive:
+		return -1
+	default:
+		return 1
+	}
+}
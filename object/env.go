@@ -1,15 +1,96 @@
 package object
 
+import "errors"
+
+var (
+	ErrUndeclared = errors.New("identifier not found")
+	ErrConstant   = errors.New("cannot assign to constant")
+	ErrRedeclared = errors.New("cannot redeclare constant")
+	ErrCallDepth  = errors.New("maximum call depth exceeded")
+)
+
+// DefaultMaxCallDepth is how deeply script functions may call one another
+// before failing, unless changed with SetMaxCallDepth. It keeps runaway
+// recursion well clear of exhausting the Go stack, which cannot be recovered.
+const DefaultMaxCallDepth = 10000
+
+// ArithmeticMode selects what integer arithmetic does on overflowing int64.
+type ArithmeticMode int
+
+const (
+	WrapArithmetic    ArithmeticMode = iota // wrap around, as Go does
+	CheckedArithmetic                       // fail with an OverflowError
+	BigArithmetic                           // promote the result to a BigInt
+)
+
 type Environment struct {
 	*Environment
 	s map[string]Object
+
+	// consts holds the names in s declared with `const`.
+	consts map[string]bool
+
+	arithmetic ArithmeticMode
+
+	// calls is shared by every scope descending from the same top level
+	// scope, so it counts calls however they are nested.
+	calls *callDepth
 }
 
+type callDepth struct {
+	depth, max int
+}
+
+// NewEnvironment returns a scope enclosed by env, or a top level scope if env
+// is nil. The scope inherits env's arithmetic mode and call depth limit.
 func NewEnvironment(env *Environment) *Environment {
-	return &Environment{
+	e := &Environment{
 		Environment: env,
 		s:           make(map[string]Object),
 	}
+	if env != nil {
+		e.arithmetic = env.arithmetic
+		e.calls = env.calls
+	} else {
+		e.calls = &callDepth{max: DefaultMaxCallDepth}
+	}
+	return e
+}
+
+// SetArithmetic sets the arithmetic mode of scripts evaluated in e. Scopes
+// take their mode from their enclosing scope when created, so it should be
+// set on a top level scope before evaluating anything in it.
+func (e *Environment) SetArithmetic(mode ArithmeticMode) {
+	e.arithmetic = mode
+}
+
+func (e *Environment) Arithmetic() ArithmeticMode {
+	return e.arithmetic
+}
+
+// SetMaxCallDepth sets how deeply script functions evaluated in e, or in any
+// scope sharing its top level scope, may call one another.
+func (e *Environment) SetMaxCallDepth(max int) {
+	e.calls.max = max
+}
+
+func (e *Environment) MaxCallDepth() int {
+	return e.calls.max
+}
+
+// EnterCall records a call made in e, failing with ErrCallDepth if it would
+// exceed the maximum call depth. Each successful EnterCall must be paired with
+// an ExitCall once the call returns.
+func (e *Environment) EnterCall() error {
+	if e.calls.depth >= e.calls.max {
+		return ErrCallDepth
+	}
+	e.calls.depth++
+	return nil
+}
+
+func (e *Environment) ExitCall() {
+	e.calls.depth--
 }
 
 func (e *Environment) Get(name string) (Object, bool) {
@@ -20,7 +101,45 @@ func (e *Environment) Get(name string) (Object, bool) {
 	return o, ok
 }
 
+// Set binds name in this scope, replacing any existing binding, even a
+// constant one. Scripts bind names through Declare, which respects constants.
 func (e *Environment) Set(name string, val Object) Object {
 	e.s[name] = val
+	delete(e.consts, name)
 	return val
 }
+
+// Declare binds name in this scope, as a constant if constant is set. A
+// constant cannot be redeclared in the same scope, nor declared over a name
+// the scope already holds, though inner scopes may shadow it.
+func (e *Environment) Declare(name string, val Object, constant bool) error {
+	if _, declared := e.s[name]; e.consts[name] || declared && constant {
+		return ErrRedeclared
+	}
+
+	e.s[name] = val
+	if constant {
+		if e.consts == nil {
+			e.consts = make(map[string]bool)
+		}
+		e.consts[name] = true
+	}
+	return nil
+}
+
+// Assign rebinds an existing name in the nearest scope which declares it. It
+// fails with ErrUndeclared if there is no such scope, and with ErrConstant if
+// the name is a constant there.
+func (e *Environment) Assign(name string, val Object) error {
+	for env := e; env != nil; env = env.Environment {
+		if _, ok := env.s[name]; ok {
+			if env.consts[name] {
+				return ErrConstant
+			}
+			env.s[name] = val
+			return nil
+		}
+	}
+
+	return ErrUndeclared
+}
@@ -4,15 +4,21 @@ type ObjectType string
 
 const (
 	IntegerType     = "INTEGER"
+	BigIntType      = "BIGINT"
+	RationalType    = "RATIONAL"
 	BooleanType     = "BOOLEAN"
 	NullType        = "NULL"
 	ReturnValueType = "RETURN_VALUE"
 	ErrorType       = "ERROR"
+	ErrorValueType  = "ERROR_VALUE"
 	FunctionType    = "FUNCTION"
 	StringType      = "STRING"
 	BuiltinType     = "BUILTIN"
 	ArrayType       = "ARRAY"
 	HashType        = "HASH"
+	BreakType       = "BREAK"
+	ContinueType    = "CONTINUE"
+	RangeType       = "RANGE"
 )
 
 type Object interface {
@@ -20,6 +26,8 @@ type Object interface {
 	Inspect() string
 }
 
+// Hashable objects can be used as hash keys.
 type Hashable interface {
+	Object
 	HashKey() HashKey
 }
@@ -1,9 +1,13 @@
 package object_test
 
 import (
+	"cmp"
+	"errors"
+	"math/big"
 	"testing"
 
 	"git.tigh.dev/tigh-latte/monkeyscript/object"
+	"git.tigh.dev/tigh-latte/monkeyscript/token"
 )
 
 func TestStringHashKey(t *testing.T) {
@@ -25,3 +29,345 @@ func TestStringHashKey(t *testing.T) {
 		t.Errorf("strings with different content have same hash keys")
 	}
 }
+
+func TestArrayHashKey(t *testing.T) {
+	pair1 := &object.Array{Elements: []object.Object{&object.Integer{Value: 1}, &object.String{Value: "GBP"}}}
+	pair2 := &object.Array{Elements: []object.Object{&object.Integer{Value: 1}, &object.String{Value: "GBP"}}}
+	diff := &object.Array{Elements: []object.Object{&object.String{Value: "GBP"}, &object.Integer{Value: 1}}}
+
+	if pair1.HashKey() != pair2.HashKey() {
+		t.Errorf("arrays with same content have different hash keys")
+	}
+
+	if pair1.HashKey() == diff.HashKey() {
+		t.Errorf("arrays with different content have same hash keys")
+	}
+}
+
+func TestKeyOf(t *testing.T) {
+	arr := &object.Array{Elements: []object.Object{&object.Integer{Value: 1}}}
+
+	key, ok := object.KeyOf(arr)
+	if !ok {
+		t.Fatalf("array of integers is not usable as a key")
+	}
+
+	arr.Elements = append(arr.Elements, &object.Integer{Value: 2})
+	if key.Inspect() != "[1]" {
+		t.Errorf("key changed with the array it was made from. got=%s", key.Inspect())
+	}
+
+	nested := &object.Array{Elements: []object.Object{arr, &object.Function{}}}
+	if _, ok := object.KeyOf(nested); ok {
+		t.Errorf("array holding a function is usable as a key")
+	}
+	if _, ok := object.KeyOf(&object.Hash{}); ok {
+		t.Errorf("hash is usable as a key")
+	}
+}
+
+// collidingKey is a host-defined key type whose hash keys always collide.
+type collidingKey struct {
+	name string
+}
+
+func (c *collidingKey) Type() object.ObjectType { return "COLLIDING" }
+func (c *collidingKey) Inspect() string         { return c.name }
+func (c *collidingKey) HashKey() object.HashKey {
+	return object.HashKey{Type: c.Type(), Value: 42}
+}
+
+func TestHashCollisions(t *testing.T) {
+	a, b, c := &collidingKey{"a"}, &collidingKey{"b"}, &collidingKey{"c"}
+
+	hash := object.NewHash()
+	hash.Set(a, &object.Integer{Value: 1})
+	hash.Set(b, &object.Integer{Value: 2})
+	hash.Set(c, &object.Integer{Value: 3})
+	hash.Set(a, &object.Integer{Value: 4})
+
+	if hash.Len() != 3 {
+		t.Fatalf("colliding keys overwrote each other. got len=%d", hash.Len())
+	}
+
+	hash.Delete(b)
+	if _, ok := hash.Get(b); ok {
+		t.Errorf("deleted key is still present")
+	}
+
+	if hash.Inspect() != "{a:4, c:3}" {
+		t.Errorf("wrong pairs after collisions. got=%s", hash.Inspect())
+	}
+}
+
+// money is a host-defined type which takes part in equality and ordering.
+type money struct {
+	pence int64
+}
+
+func (m *money) Type() object.ObjectType    { return "MONEY" }
+func (m *money) Inspect() string            { return "money" }
+func (m *money) Equal(o object.Object) bool { return m.pence == o.(*money).pence }
+func (m *money) Compare(o object.Object) int {
+	return cmp.Compare(m.pence, o.(*money).pence)
+}
+
+func TestEnvironmentDeclare(t *testing.T) {
+	outer := object.NewEnvironment(nil)
+	one := &object.Integer{Value: 1}
+
+	if err := outer.Declare("x", one, false); err != nil {
+		t.Fatalf("declare x: %v", err)
+	}
+	if err := outer.Declare("x", one, false); err != nil {
+		t.Errorf("redeclare let x: %v", err)
+	}
+	if err := outer.Declare("x", one, true); !errors.Is(err, object.ErrRedeclared) {
+		t.Errorf("const over let x: want %v, got %v", object.ErrRedeclared, err)
+	}
+	if err := outer.Declare("K", one, true); err != nil {
+		t.Fatalf("declare K: %v", err)
+	}
+	if err := outer.Declare("K", one, false); !errors.Is(err, object.ErrRedeclared) {
+		t.Errorf("let over const K: want %v, got %v", object.ErrRedeclared, err)
+	}
+
+	inner := object.NewEnvironment(outer)
+	if err := inner.Assign("K", one); !errors.Is(err, object.ErrConstant) {
+		t.Errorf("assign K: want %v, got %v", object.ErrConstant, err)
+	}
+	if err := inner.Assign("y", one); !errors.Is(err, object.ErrUndeclared) {
+		t.Errorf("assign y: want %v, got %v", object.ErrUndeclared, err)
+	}
+	if err := inner.Declare("K", one, false); err != nil {
+		t.Errorf("shadow K: %v", err)
+	}
+	if err := inner.Assign("K", one); err != nil {
+		t.Errorf("assign shadowed K: %v", err)
+	}
+}
+
+func TestErrorKinds(t *testing.T) {
+	tests := []struct {
+		err      *object.Error
+		kind     object.ErrorKind
+		expected string
+	}{
+		{&object.Error{Message: "boom"}, object.RuntimeError, "RuntimeError: boom"},
+		{&object.Error{Message: "bad", Kind: object.TypeError}, object.TypeError, "TypeError: bad"},
+		{
+			&object.Error{Message: "x", Kind: object.NameError, Pos: token.Position{Line: 2, Column: 4}},
+			object.NameError,
+			"2:4: NameError: x",
+		},
+	}
+
+	for _, test := range tests {
+		if test.err.ErrorKind() != test.kind {
+			t.Errorf("wrong kind. want=%s, got=%s", test.kind, test.err.ErrorKind())
+		}
+		if test.err.Error() != test.expected {
+			t.Errorf("wrong message. want=%q, got=%q", test.expected, test.err.Error())
+		}
+		if !errors.Is(test.err, test.kind) {
+			t.Errorf("expected errors.Is(%v, %s)", test.err, test.kind)
+		}
+		if errors.Is(test.err, object.UserError) {
+			t.Errorf("unexpected errors.Is(%v, %s)", test.err, object.UserError)
+		}
+	}
+}
+
+func TestEnvironmentCallDepth(t *testing.T) {
+	global := object.NewEnvironment(nil)
+	global.SetMaxCallDepth(2)
+	inner := object.NewEnvironment(object.NewEnvironment(global))
+
+	if err := global.EnterCall(); err != nil {
+		t.Fatalf("first call failed: %v", err)
+	}
+	if err := inner.EnterCall(); err != nil {
+		t.Fatalf("second call failed: %v", err)
+	}
+	if err := inner.EnterCall(); !errors.Is(err, object.ErrCallDepth) {
+		t.Errorf("expected ErrCallDepth past the limit. got=%v", err)
+	}
+
+	inner.ExitCall()
+	if err := global.EnterCall(); err != nil {
+		t.Errorf("call after exit failed: %v", err)
+	}
+
+	if object.NewEnvironment(nil).MaxCallDepth() != object.DefaultMaxCallDepth {
+		t.Errorf("new environment does not have the default call depth")
+	}
+}
+
+func TestStackTrace(t *testing.T) {
+	err := &object.Error{
+		Message: "boom",
+		Kind:    object.UserError,
+		Pos:     token.Position{Line: 3, Column: 5},
+		Trace: []object.Frame{
+			{Function: "len", Builtin: true, Pos: token.Position{Line: 3, Column: 8}},
+			{Function: "", Pos: token.Position{Line: 7, Column: 2}},
+			{Function: "run"},
+		},
+	}
+
+	expected := "UserError: boom\n" +
+		"\nlen(...)\n\t<builtin>" +
+		"\n<anonymous>(...)\n\t3:8" +
+		"\nrun(...)\n\t7:2" +
+		"\nmain\n\t?"
+	if err.StackTrace() != expected {
+		t.Errorf("wrong stack trace.\nwant:\n%s\ngot:\n%s", expected, err.StackTrace())
+	}
+
+	call := token.Position{Line: 1, Column: 9}
+	recursive := &object.Error{
+		Message: "too deep",
+		Kind:    object.RecursionError,
+		Pos:     call,
+		Trace:   []object.Frame{{Function: "f", Pos: call}, {Function: "f", Pos: call}, {Function: "f", Pos: call}, {Function: "g", Pos: call}},
+	}
+
+	expected = "RecursionError: too deep\n" +
+		"\nf(...)\n\t1:9" +
+		"\n... repeated 2 more times" +
+		"\ng(...)\n\t1:9" +
+		"\nmain\n\t1:9"
+	if recursive.StackTrace() != expected {
+		t.Errorf("wrong stack trace.\nwant:\n%s\ngot:\n%s", expected, recursive.StackTrace())
+	}
+}
+
+func TestBigInt(t *testing.T) {
+	huge, _ := new(big.Int).SetString("18446744073709551616", 10)
+
+	if _, ok := object.NewInteger(big.NewInt(42)).(*object.Integer); !ok {
+		t.Errorf("NewInteger(42) is not an Integer")
+	}
+	b1, ok := object.NewInteger(huge).(*object.BigInt)
+	if !ok {
+		t.Fatalf("NewInteger(2^64) is not a BigInt")
+	}
+	b2 := &object.BigInt{Value: new(big.Int).Set(huge)}
+	neg := &object.BigInt{Value: new(big.Int).Neg(huge)}
+
+	if b1.HashKey() != b2.HashKey() {
+		t.Errorf("equal BigInts have different hash keys")
+	}
+	if b1.HashKey() == neg.HashKey() {
+		t.Errorf("BigInts of opposite sign have the same hash key")
+	}
+	if !object.Equal(b1, b2) || object.Equal(b1, neg) {
+		t.Errorf("wrong BigInt equality")
+	}
+
+	one := &object.Integer{Value: 1}
+	if object.Compare(neg, one) >= 0 || object.Compare(one, b1) >= 0 || object.Compare(b1, one) <= 0 {
+		t.Errorf("BigInts and Integers are not ordered by value")
+	}
+	if object.Compare(b1, &object.String{Value: "a"}) >= 0 {
+		t.Errorf("BigInt does not sort before String")
+	}
+}
+
+func TestRational(t *testing.T) {
+	if _, ok := object.NewRational(big.NewRat(4, 2)).(*object.Integer); !ok {
+		t.Errorf("NewRational(4/2) is not an Integer")
+	}
+	half, ok := object.NewRational(big.NewRat(1, 2)).(*object.Rational)
+	if !ok {
+		t.Fatalf("NewRational(1/2) is not a Rational")
+	}
+	other := &object.Rational{Value: big.NewRat(2, 4)}
+
+	if half.Inspect() != "1/2" {
+		t.Errorf("wrong Inspect. got=%q", half.Inspect())
+	}
+	if half.HashKey() != other.HashKey() || !object.Equal(half, other) {
+		t.Errorf("equal Rationals are not equal")
+	}
+
+	zero, one := &object.Integer{Value: 0}, &object.Integer{Value: 1}
+	if object.Compare(zero, half) >= 0 || object.Compare(half, one) >= 0 || object.Equal(half, one) {
+		t.Errorf("Rationals and Integers are not ordered by value")
+	}
+}
+
+func TestCyclicValues(t *testing.T) {
+	one := &object.Integer{Value: 1}
+	a := &object.Array{Elements: []object.Object{one}}
+	a.Elements = append(a.Elements, a)
+	b := &object.Array{Elements: []object.Object{one}}
+	b.Elements = append(b.Elements, b)
+	c := &object.Array{Elements: []object.Object{&object.Integer{Value: 2}}}
+	c.Elements = append(c.Elements, c)
+
+	if !object.Equal(a, a) || !object.Equal(a, b) || object.Equal(a, c) {
+		t.Errorf("wrong equality of cyclic arrays")
+	}
+	if object.Compare(a, a) != 0 || object.Compare(a, b) != 0 || object.Compare(a, c) >= 0 {
+		t.Errorf("wrong order of cyclic arrays")
+	}
+	if a.Inspect() != "[1, [...]]" {
+		t.Errorf("wrong Inspect of cyclic array. got=%q", a.Inspect())
+	}
+	if a.HashKey() != b.HashKey() {
+		t.Errorf("equal cyclic arrays have different hash keys")
+	}
+	if _, ok := object.KeyOf(a); ok {
+		t.Errorf("cyclic array is usable as a hash key")
+	}
+
+	h := object.NewHash()
+	h.Set(&object.String{Value: "self"}, h)
+	if h.Inspect() != "{self:{...}}" {
+		t.Errorf("wrong Inspect of cyclic hash. got=%q", h.Inspect())
+	}
+	if !object.Equal(h, h) || object.Compare(h, h) != 0 {
+		t.Errorf("cyclic hash is not equal to itself")
+	}
+
+	nested := &object.Array{Elements: []object.Object{a, a}}
+	if nested.Inspect() != "[[1, [...]], [1, [...]]]" {
+		t.Errorf("repeated, non-cyclic elements are elided. got=%q", nested.Inspect())
+	}
+}
+
+func TestEqualAndCompare(t *testing.T) {
+	one := &object.Integer{Value: 1}
+	str := &object.String{Value: "a"}
+	arr := &object.Array{Elements: []object.Object{one, str}}
+
+	tests := []struct {
+		a, b    object.Object
+		equal   bool
+		compare int
+	}{
+		{one, &object.Integer{Value: 1}, true, 0},
+		{one, &object.Integer{Value: 2}, false, -1},
+		{str, &object.String{Value: "a"}, true, 0},
+		{one, str, false, -1},
+		{str, arr, false, -1},
+		{arr, &object.Array{Elements: []object.Object{one, str}}, true, 0},
+		{arr, &object.Array{Elements: []object.Object{one}}, false, 1},
+		{&money{pence: 5}, &money{pence: 5}, true, 0},
+		{&money{pence: 5}, &money{pence: 9}, false, -1},
+		{&money{pence: 5}, arr, false, 1},
+	}
+
+	for _, test := range tests {
+		if object.Equal(test.a, test.b) != test.equal {
+			t.Errorf("Equal(%s, %s) wrong. want=%t", test.a.Inspect(), test.b.Inspect(), test.equal)
+		}
+		if got := object.Compare(test.a, test.b); got != test.compare {
+			t.Errorf("Compare(%s, %s) wrong. want=%d, got=%d", test.a.Inspect(), test.b.Inspect(), test.compare, got)
+		}
+		if got := object.Compare(test.b, test.a); got != -test.compare {
+			t.Errorf("Compare(%s, %s) wrong. want=%d, got=%d", test.b.Inspect(), test.a.Inspect(), -test.compare, got)
+		}
+	}
+}
@@ -2,11 +2,16 @@ package object
 
 import (
 	"bytes"
+	"encoding/binary"
 	"hash/fnv"
+	"math"
+	"math/big"
+	"slices"
 	"strconv"
 	"strings"
 
 	"git.tigh.dev/tigh-latte/monkeyscript/ast"
+	"git.tigh.dev/tigh-latte/monkeyscript/token"
 )
 
 type Integer struct {
@@ -21,6 +26,44 @@ func (i *Integer) Type() ObjectType {
 	return IntegerType
 }
 
+// BigInt is an integer beyond the range of an Integer, produced only under
+// BigArithmetic. Results which fit in an Integer are always made Integers, so
+// a BigInt never holds a value an Integer could. Value must not be modified.
+type BigInt struct {
+	Value *big.Int
+}
+
+// NewInteger returns v as an Integer if it fits in one, and as a BigInt
+// otherwise.
+func NewInteger(v *big.Int) Object {
+	if v.IsInt64() {
+		return &Integer{Value: v.Int64()}
+	}
+	return &BigInt{Value: v}
+}
+
+func (b *BigInt) Type() ObjectType { return BigIntType }
+func (b *BigInt) Inspect() string  { return b.Value.String() }
+
+// Rational is an exact fraction, such as the result of `7 // 30`. Like a
+// big.Rat it is always in lowest terms, and a whole number is always made an
+// Integer or BigInt instead, so a Rational never equals an integer. Value must
+// not be modified.
+type Rational struct {
+	Value *big.Rat
+}
+
+// NewRational returns v as a Rational, or as an integer if it is whole.
+func NewRational(v *big.Rat) Object {
+	if v.IsInt() {
+		return NewInteger(new(big.Int).Set(v.Num()))
+	}
+	return &Rational{Value: v}
+}
+
+func (r *Rational) Type() ObjectType { return RationalType }
+func (r *Rational) Inspect() string  { return r.Value.String() }
+
 type Boolean struct {
 	Value bool
 }
@@ -55,8 +98,56 @@ func (r *ReturnValue) Inspect() string {
 	return r.Value.Inspect()
 }
 
+// Break and Continue are loop control signals. Like ReturnValue they unwind
+// enclosing blocks until they reach the loop they belong to.
+type Break struct{}
+
+func (b *Break) Type() ObjectType { return BreakType }
+func (b *Break) Inspect() string  { return "break" }
+
+type Continue struct{}
+
+func (c *Continue) Type() ObjectType { return ContinueType }
+func (c *Continue) Inspect() string  { return "continue" }
+
+// ErrorKind classifies an Error, so scripts can tell failures apart by
+// `e.kind` and hosts by errors.Is(err, kind).
+type ErrorKind string
+
+const (
+	RuntimeError   ErrorKind = "RuntimeError"   // any failure not covered below
+	TypeError      ErrorKind = "TypeError"      // a value of the wrong type
+	NameError      ErrorKind = "NameError"      // an undeclared identifier
+	ArityError     ErrorKind = "ArityError"     // wrong arguments to a function
+	IndexError     ErrorKind = "IndexError"     // an index out of range
+	ValueError     ErrorKind = "ValueError"     // a value of the right type but unusable
+	DivisionByZero ErrorKind = "DivisionByZero" // integer division by zero
+	PatternError   ErrorKind = "PatternError"   // a value not matching a destructuring pattern
+	ImmutableError ErrorKind = "ImmutableError" // a write to a constant or frozen value
+	OverflowError  ErrorKind = "OverflowError"  // integer overflow under CheckedArithmetic
+	RecursionError ErrorKind = "RecursionError" // calls nested beyond the maximum call depth
+	UserError      ErrorKind = "UserError"      // a value raised by `throw`
+	InternalError  ErrorKind = "InternalError"  // a Go panic in a builtin or the evaluator
+)
+
+func (k ErrorKind) Error() string { return string(k) }
+
+// Error is a failure unwinding the script until a try expression catches it.
 type Error struct {
 	Message string
+	Kind    ErrorKind      // RuntimeError if empty
+	Pos     token.Position // where the error was raised, if known
+	Value   Object         // the value thrown, for errors raised by `throw`
+
+	// Trace holds the calls the error has unwound so far, innermost first.
+	Trace []Frame
+}
+
+// Frame is a call on an Error's stack trace.
+type Frame struct {
+	Function string         // the function called, empty if anonymous
+	Builtin  bool           // whether the function is a builtin
+	Pos      token.Position // the position of the call
 }
 
 func (e *Error) Type() ObjectType {
@@ -64,11 +155,106 @@ func (e *Error) Type() ObjectType {
 }
 
 func (e *Error) Inspect() string {
+	if e.Pos.IsValid() {
+		return "ERROR: " + e.Pos.String() + ": " + e.Message
+	}
 	return "ERROR: " + e.Message
 }
 
+// ErrorKind returns the kind of e, defaulting to RuntimeError.
+func (e *Error) ErrorKind() ErrorKind {
+	if e.Kind == "" {
+		return RuntimeError
+	}
+	return e.Kind
+}
+
+// Error implements error, so hosts can handle a script's errors like any
+// other.
+func (e *Error) Error() string {
+	msg := string(e.ErrorKind()) + ": " + e.Message
+	if e.Pos.IsValid() {
+		return e.Pos.String() + ": " + msg
+	}
+	return msg
+}
+
+// StackTrace renders e and its trace in the manner of a Go panic: each
+// function it unwound, innermost first, followed by the position execution
+// had reached in it, and lastly the top level of the script. Runs of
+// identical entries, as left by recursion, are written once with a count.
+func (e *Error) StackTrace() string {
+	bb := new(bytes.Buffer)
+
+	bb.WriteString(string(e.ErrorKind()) + ": " + e.Message + "\n")
+
+	var last string
+	repeats := 0
+	writeRepeats := func() {
+		if repeats > 0 {
+			bb.WriteString("\n... repeated " + strconv.Itoa(repeats) + " more times")
+		}
+		repeats = 0
+	}
+
+	pos := e.Pos
+	for _, frame := range e.Trace {
+		name := frame.Function
+		if name == "" {
+			name = "<anonymous>"
+		}
+		entry := "\n" + name + "(...)\n\t"
+		if frame.Builtin {
+			entry += "<builtin>"
+		} else {
+			entry += positionString(pos)
+		}
+		pos = frame.Pos
+
+		if entry == last {
+			repeats++
+			continue
+		}
+		writeRepeats()
+		bb.WriteString(entry)
+		last = entry
+	}
+	writeRepeats()
+	bb.WriteString("\nmain\n\t" + positionString(pos))
+
+	return bb.String()
+}
+
+func positionString(pos token.Position) string {
+	if !pos.IsValid() {
+		return "?"
+	}
+	return pos.String()
+}
+
+// Is reports whether e is of the kind target, so that
+// errors.Is(err, object.TypeError) holds for a TypeError.
+func (e *Error) Is(target error) bool {
+	kind, ok := target.(ErrorKind)
+	return ok && kind == e.ErrorKind()
+}
+
+// ErrorValue is an Error caught by a catch clause. Unlike an Error it is an
+// ordinary value, which a script can inspect, pass around or throw again.
+type ErrorValue struct {
+	Err *Error
+}
+
+func (ev *ErrorValue) Type() ObjectType { return ErrorValueType }
+func (ev *ErrorValue) Inspect() string {
+	return string(ev.Err.ErrorKind()) + ": " + ev.Err.Message
+}
+
 type Function struct {
+	Name       string // empty for anonymous functions
 	Parameters []*ast.Identifier
+	Defaults   map[string]ast.Expression
+	Rest       *ast.Identifier
 	Body       *ast.BlockStatement
 	Env        *Environment
 }
@@ -83,9 +269,17 @@ func (f *Function) Inspect() string {
 	params := make([]string, len(f.Parameters))
 	for i, param := range f.Parameters {
 		params[i] = param.String()
+		if def, ok := f.Defaults[param.Value]; ok {
+			params[i] += " = " + def.String()
+		}
+	}
+	if f.Rest != nil {
+		params = append(params, "..."+f.Rest.String())
 	}
 
-	bb.WriteString("fn (")
+	bb.WriteString("fn ")
+	bb.WriteString(f.Name)
+	bb.WriteString("(")
 	bb.WriteString(strings.Join(params, ", "))
 	bb.WriteString(") {\n")
 	bb.WriteString(f.Body.String())
@@ -94,6 +288,8 @@ func (f *Function) Inspect() string {
 	return bb.String()
 }
 
+// String is an immutable string. Scripts index, slice, measure and iterate
+// strings by rune, so `"héllo"[1]` is "é".
 type String struct {
 	Value string
 }
@@ -103,34 +299,163 @@ func (s *String) Inspect() string  { return s.Value }
 
 type BuiltinFunction func(args ...Object) Object
 
+// KeywordBuiltinFunction is a BuiltinFunction which also accepts keyword
+// arguments, e.g. `round(x, mode: "half_even")`.
+type KeywordBuiltinFunction func(kwargs Keywords, args ...Object) Object
+
+// Builtin is a function implemented in Go. Calls with keyword arguments are
+// passed to KwFn, and are an error if it is nil; other calls go to Fn, or to
+// KwFn if Fn is nil.
 type Builtin struct {
-	Fn BuiltinFunction
+	Name string // the name the builtin is known by, for stack traces
+	Fn   BuiltinFunction
+	KwFn KeywordBuiltinFunction
+}
+
+// KeywordArgument is a single `name: value` argument passed to a function.
+type KeywordArgument struct {
+	Name  string
+	Value Object
+}
+
+// Keywords holds keyword arguments in the order they were written.
+type Keywords []KeywordArgument
+
+// Get returns the value of the named keyword argument.
+func (k Keywords) Get(name string) (Object, bool) {
+	for _, kw := range k {
+		if kw.Name == name {
+			return kw.Value, true
+		}
+	}
+
+	return nil, false
 }
 
 func (b *Builtin) Type() ObjectType { return BuiltinType }
 func (b *Builtin) Inspect() string  { return "builtin function" }
 
+// Array is a reference type: every binding, closure and collection holding
+// the same Array observes mutations made through any of them. Builtins such
+// as `push` and `rest` return copies, whereas `append!`, `set!`, `delete!`
+// and index assignment mutate in place.
 type Array struct {
 	Elements []Object
+	Frozen   bool // set by `freeze`, after which the array must not change
 }
 
 func (a *Array) Type() ObjectType { return ArrayType }
 func (a *Array) Inspect() string {
-	bb := new(bytes.Buffer)
-
-	elems := []string{}
+	return inspect(a, &visiting{})
+}
 
-	for _, e := range a.Elements {
-		elems = append(elems, e.Inspect())
+// inspect is Inspect for values which may contain themselves, writing an
+// array or hash met again within itself as `[...]` or `{...}`.
+func inspect(obj Object, v *visiting) string {
+	switch obj := obj.(type) {
+	case *Array:
+		if !v.enter(obj, nil) {
+			return "[...]"
+		}
+		defer v.leave(obj, nil)
+
+		bb := new(bytes.Buffer)
+
+		elems := []string{}
+
+		for _, e := range obj.Elements {
+			elems = append(elems, inspect(e, v))
+		}
+
+		bb.WriteByte('[')
+		bb.WriteString(strings.Join(elems, ", "))
+		bb.WriteByte(']')
+
+		return bb.String()
+	case *Hash:
+		if !v.enter(obj, nil) {
+			return "{...}"
+		}
+		defer v.leave(obj, nil)
+
+		bb := new(bytes.Buffer)
+
+		pairs := make([]string, 0, obj.Len())
+		for _, pair := range obj.Pairs() {
+			pairs = append(pairs, inspect(pair.Key, v)+":"+inspect(pair.Value, v))
+		}
+
+		bb.WriteRune('{')
+		bb.WriteString(strings.Join(pairs, ", "))
+		bb.WriteRune('}')
+
+		return bb.String()
+	default:
+		return obj.Inspect()
 	}
+}
+
+// Range is a lazily evaluated arithmetic sequence of integers from Start
+// towards End, Step apart. End is included unless Exclusive is set. A Range is
+// never materialised, so large ranges do not allocate.
+type Range struct {
+	Start     int64
+	End       int64
+	Step      int64
+	Exclusive bool
+}
+
+func (r *Range) Type() ObjectType { return RangeType }
+func (r *Range) Inspect() string {
+	var bb bytes.Buffer
 
-	bb.WriteByte('[')
-	bb.WriteString(strings.Join(elems, ", "))
-	bb.WriteByte(']')
+	bb.WriteString(strconv.FormatInt(r.Start, 10))
+	if r.Exclusive {
+		bb.WriteString("..<")
+	} else {
+		bb.WriteString("..")
+	}
+	bb.WriteString(strconv.FormatInt(r.End, 10))
+	if r.Step != 1 {
+		bb.WriteString(" step ")
+		bb.WriteString(strconv.FormatInt(r.Step, 10))
+	}
 
 	return bb.String()
 }
 
+// Len returns the number of integers in the range.
+func (r *Range) Len() int64 {
+	var span uint64
+	switch {
+	case r.Step > 0 && r.Start <= r.End:
+		span = uint64(r.End) - uint64(r.Start)
+	case r.Step < 0 && r.Start >= r.End:
+		span = uint64(r.Start) - uint64(r.End)
+	default:
+		return 0
+	}
+
+	step := uint64(r.Step)
+	if r.Step < 0 {
+		step = uint64(-r.Step)
+	}
+
+	n := span/step + 1
+	if r.Exclusive && span%step == 0 {
+		n--
+	}
+	if n > math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return int64(n)
+}
+
+// At returns the i'th integer in the range. It does not check bounds.
+func (r *Range) At(i int64) int64 {
+	return r.Start + i*r.Step
+}
+
 type HashKey struct {
 	Type  ObjectType
 	Value uint64
@@ -150,6 +475,24 @@ func (i *Integer) HashKey() HashKey {
 	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
 }
 
+func (b *BigInt) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write([]byte{byte(b.Value.Sign() + 1)})
+	h.Write(b.Value.Bytes())
+
+	return HashKey{Type: b.Type(), Value: h.Sum64()}
+}
+
+func (r *Rational) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write([]byte{byte(r.Value.Sign() + 1)})
+	h.Write(r.Value.Num().Bytes())
+	h.Write([]byte{'/'})
+	h.Write(r.Value.Denom().Bytes())
+
+	return HashKey{Type: r.Type(), Value: h.Sum64()}
+}
+
 func (s *String) HashKey() HashKey {
 	h := fnv.New64a()
 	h.Write([]byte(s.Value))
@@ -157,30 +500,175 @@ func (s *String) HashKey() HashKey {
 	return HashKey{Type: s.Type(), Value: h.Sum64()}
 }
 
+// HashKey combines the hash keys of the array's elements, so the array must
+// only hold hashable values. See KeyOf.
+func (a *Array) HashKey() HashKey {
+	return a.hashKey(&visiting{})
+}
+
+func (a *Array) hashKey(v *visiting) HashKey {
+	h := fnv.New64a()
+	if !v.enter(a, nil) {
+		return HashKey{Type: a.Type(), Value: h.Sum64()}
+	}
+	defer v.leave(a, nil)
+
+	buf := make([]byte, 0, 8)
+	for _, elem := range a.Elements {
+		h.Write([]byte(elem.Type()))
+		var key HashKey
+		switch elem := elem.(type) {
+		case *Array:
+			key = elem.hashKey(v)
+		case Hashable:
+			key = elem.HashKey()
+		default:
+			continue
+		}
+		h.Write(binary.LittleEndian.AppendUint64(buf[:0], key.Value))
+	}
+
+	return HashKey{Type: a.Type(), Value: h.Sum64()}
+}
+
+func (r *Range) HashKey() HashKey {
+	h := fnv.New64a()
+	buf := make([]byte, 0, 25)
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(r.Start))
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(r.End))
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(r.Step))
+	if r.Exclusive {
+		buf = append(buf, 1)
+	}
+	h.Write(buf)
+
+	return HashKey{Type: r.Type(), Value: h.Sum64()}
+}
+
+// KeyOf returns obj as a hash key. Arrays are usable as keys when all of
+// their elements are, and do not contain themselves, and are copied and
+// frozen so that neither the original array nor the stored key can change.
+func KeyOf(obj Object) (Hashable, bool) {
+	return keyOf(obj, &visiting{})
+}
+
+func keyOf(obj Object, v *visiting) (Hashable, bool) {
+	switch obj := obj.(type) {
+	case *Array:
+		if !v.enter(obj, nil) {
+			return nil, false
+		}
+		defer v.leave(obj, nil)
+
+		elems := make([]Object, len(obj.Elements))
+		for i, elem := range obj.Elements {
+			key, ok := keyOf(elem, v)
+			if !ok {
+				return nil, false
+			}
+			elems[i] = key
+		}
+		return &Array{Elements: elems, Frozen: true}, true
+	case Hashable:
+		return obj, true
+	}
+
+	return nil, false
+}
+
 type HashPair struct {
 	Key   Object
 	Value Object
 }
 
+// Hash shares the reference semantics of Array. It remembers the order in
+// which keys were first set, and Pairs, Inspect and iteration all follow it.
+// The zero value is an empty hash ready to use.
 type Hash struct {
-	Pairs map[HashKey]HashPair
+	// buckets holds the pairs stored under each hash key. Keys whose hash
+	// keys collide share a bucket, and are told apart with Equal.
+	buckets map[HashKey][]*HashPair
+
+	// order holds every pair in insertion order.
+	order []*HashPair
+
+	Frozen bool // set by `freeze`, after which the hash must not change
+}
+
+func NewHash() *Hash {
+	return &Hash{buckets: make(map[HashKey][]*HashPair)}
 }
 
 func (h *Hash) Type() ObjectType {
 	return HashType
 }
 
-func (h *Hash) Inspect() string {
-	bb := new(bytes.Buffer)
+// Len returns the number of pairs in the hash.
+func (h *Hash) Len() int {
+	return len(h.order)
+}
 
-	pairs := make([]string, 0, len(h.Pairs))
-	for _, pair := range h.Pairs {
-		pairs = append(pairs, pair.Key.Inspect()+":"+pair.Value.Inspect())
+func (h *Hash) find(key Hashable) *HashPair {
+	for _, pair := range h.buckets[key.HashKey()] {
+		if Equal(pair.Key, key) {
+			return pair
+		}
 	}
+	return nil
+}
 
-	bb.WriteRune('{')
-	bb.WriteString(strings.Join(pairs, ", "))
-	bb.WriteRune('}')
+// Get returns the pair stored under key.
+func (h *Hash) Get(key Hashable) (HashPair, bool) {
+	if pair := h.find(key); pair != nil {
+		return *pair, true
+	}
+	return HashPair{}, false
+}
 
-	return bb.String()
+// Set stores value under key. A new key goes last, while an existing key
+// keeps its place.
+func (h *Hash) Set(key Hashable, value Object) {
+	if pair := h.find(key); pair != nil {
+		pair.Value = value
+		return
+	}
+
+	if h.buckets == nil {
+		h.buckets = make(map[HashKey][]*HashPair)
+	}
+
+	pair := &HashPair{Key: key, Value: value}
+	hashed := key.HashKey()
+	h.buckets[hashed] = append(h.buckets[hashed], pair)
+	h.order = append(h.order, pair)
+}
+
+// Delete removes key from the hash, if present.
+func (h *Hash) Delete(key Hashable) {
+	pair := h.find(key)
+	if pair == nil {
+		return
+	}
+
+	hashed := key.HashKey()
+	bucket := slices.DeleteFunc(h.buckets[hashed], func(p *HashPair) bool { return p == pair })
+	if len(bucket) == 0 {
+		delete(h.buckets, hashed)
+	} else {
+		h.buckets[hashed] = bucket
+	}
+	h.order = slices.DeleteFunc(h.order, func(p *HashPair) bool { return p == pair })
+}
+
+// Pairs returns a copy of the hash's pairs in insertion order.
+func (h *Hash) Pairs() []HashPair {
+	pairs := make([]HashPair, len(h.order))
+	for i, pair := range h.order {
+		pairs[i] = *pair
+	}
+	return pairs
+}
+
+func (h *Hash) Inspect() string {
+	return inspect(h, &visiting{})
 }
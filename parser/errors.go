@@ -2,4 +2,17 @@ package parser
 
 import "errors"
 
-var ErrUnexpectedToken = errors.New("unexpected token")
+var (
+	ErrUnexpectedToken        = errors.New("unexpected token")
+	ErrInvalidAssignTarget    = errors.New("invalid assignment target")
+	ErrLoopControlOutsideLoop = errors.New("loop control outside of loop")
+	ErrInvalidParameter       = errors.New("invalid parameter")
+	ErrInvalidArgument        = errors.New("invalid argument")
+	ErrInvalidPattern         = errors.New("invalid pattern")
+	ErrConstAssign            = errors.New("cannot assign to constant")
+	ErrConstRedeclared        = errors.New("cannot redeclare constant")
+	ErrTryWithoutHandler      = errors.New("try without catch or finally")
+	ErrEmptyInterpolation     = errors.New("empty interpolation")
+
+	ErrNonExhaustiveMatch = errors.New("match has no wildcard arm")
+)
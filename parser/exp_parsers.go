@@ -1,26 +1,82 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"math/big"
 	"strconv"
+	"strings"
 
 	"git.tigh.dev/tigh-latte/monkeyscript/ast"
 	"git.tigh.dev/tigh-latte/monkeyscript/token"
 )
 
 func (p *Parser) parseIdentifier() ast.Expression {
+	if p.peekToken.Type == token.FATARROW && !p.noArrowLambda {
+		return p.parseArrowLambda()
+	}
 	return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 }
 
 func (p *Parser) parseIntegerLiteral() ast.Expression {
-	i, err := strconv.ParseInt(p.curToken.Literal, 10, 64)
+	digits, base := integerDigits(p.curToken.Literal)
+	if base == 10 && strings.ContainsAny(digits, "eE") {
+		return p.parseExponentLiteral(digits)
+	}
+	i, err := strconv.ParseInt(digits, base, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		// Whether a literal beyond int64 is usable depends on the
+		// evaluator's arithmetic mode, so it is kept as a big.Int.
+		if b, ok := new(big.Int).SetString(digits, base); ok {
+			return &ast.IntegerLiteral{Token: p.curToken, Big: b}
+		}
+	}
 	if err != nil {
-		p.errors = append(p.errors, fmt.Errorf("could not parse %q as integer: %w", p.curToken.Literal, err))
+		// A malformed literal has already been reported by the lexer.
 		return nil
 	}
 	return &ast.IntegerLiteral{Token: p.curToken, Value: i}
 }
 
+// parseExponentLiteral parses the digits of a literal such as `1e3` or
+// `25e-2` exactly, giving an IntegerLiteral if its value is whole and a
+// RationalLiteral otherwise.
+func (p *Parser) parseExponentLiteral(digits string) ast.Expression {
+	r, ok := new(big.Rat).SetString(digits)
+	if !ok {
+		// A malformed literal has already been reported by the lexer.
+		return nil
+	}
+	if !r.IsInt() {
+		return &ast.RationalLiteral{Token: p.curToken, Value: r}
+	}
+	if n := r.Num(); n.IsInt64() {
+		return &ast.IntegerLiteral{Token: p.curToken, Value: n.Int64()}
+	}
+	return &ast.IntegerLiteral{Token: p.curToken, Big: r.Num()}
+}
+
+// integerDigits strips the base prefix and digit separators from an integer
+// literal. A leading zero does not make a literal octal; only `0o` does.
+func integerDigits(literal string) (string, int) {
+	base := 10
+	if len(literal) > 2 && literal[0] == '0' {
+		switch literal[1] {
+		case 'x', 'X':
+			base = 16
+		case 'b', 'B':
+			base = 2
+		case 'o', 'O':
+			base = 8
+		}
+	}
+	if base != 10 {
+		literal = literal[2:]
+	}
+	return strings.ReplaceAll(literal, "_", ""), base
+}
+
 func (p *Parser) parsePrefixExpression() ast.Expression {
 	expression := &ast.PrefixExpression{
 		Token:    p.curToken,
@@ -31,9 +87,21 @@ func (p *Parser) parsePrefixExpression() ast.Expression {
 
 	expression.Right = p.parseExpression(PREFIX)
 
+	// -9223372036854775808 is the one literal that only fits in int64 once
+	// negated, so it is folded rather than overflowing before the negation.
+	if lit, ok := expression.Right.(*ast.IntegerLiteral); ok && expression.Operator == "-" &&
+		lit.Big != nil && lit.Big.Cmp(minInt64Magnitude) == 0 {
+		tok := expression.Token
+		tok.Type, tok.Literal = token.INT, "-"+lit.Token.Literal
+		return &ast.IntegerLiteral{Token: tok, Value: math.MinInt64}
+	}
+
 	return expression
 }
 
+// minInt64Magnitude is 9223372036854775808, the magnitude of math.MinInt64.
+var minInt64Magnitude = new(big.Int).Neg(big.NewInt(math.MinInt64))
+
 func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	expression := &ast.InfixExpression{
 		Token:    p.curToken,
@@ -52,6 +120,10 @@ func (p *Parser) parseBoolean() ast.Expression {
 	return &ast.Boolean{Token: p.curToken, Value: p.curToken.Type == token.TRUE}
 }
 
+func (p *Parser) parseNullLiteral() ast.Expression {
+	return &ast.NullLiteral{Token: p.curToken}
+}
+
 func (p *Parser) parseGroupedExpression() ast.Expression {
 	p.nextToken()
 
@@ -103,6 +175,9 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	block := &ast.BlockStatement{Token: p.curToken}
 	block.Statements = []ast.Statement{}
 
+	p.pushScope()
+	defer p.popScope()
+
 	p.nextToken()
 
 	for p.curToken.Type != token.RSQUIG && p.curToken.Type != token.EOF {
@@ -123,82 +198,256 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 		return nil
 	}
 
-	lit.Parameters = p.parseFunctionParameters()
+	if !p.parseFunctionParameters(lit, token.RPAREN) {
+		return nil
+	}
 
 	if !p.expectPeek(token.LSQUIG) {
 		return nil
 	}
 
+	// A function body starts a fresh loop context: `break` inside a closure
+	// must not reach a loop surrounding the function literal.
+	loopDepth := p.loopDepth
+	p.loopDepth = 0
+	p.pushScope()
+	p.declareParameters(lit)
 	lit.Body = p.parseBlockStatement()
+	p.popScope()
+	p.loopDepth = loopDepth
 
 	return lit
 }
 
-func (p *Parser) parseFunctionParameters() []*ast.Identifier {
-	idents := []*ast.Identifier{}
+// parseFunctionParameters parses a parameter list up to and including end,
+// which is `)` for function literals and `|` for lambdas.
+func (p *Parser) parseFunctionParameters(lit *ast.FunctionLiteral, end token.TokenType) bool {
+	lit.Parameters = []*ast.Identifier{}
 
-	if p.peekToken.Type == token.RPAREN {
+	if p.peekToken.Type == end {
 		p.nextToken()
-		return idents
+		return true
 	}
 
-	p.nextToken()
-
-	ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-	idents = append(idents, ident)
+	for {
+		p.nextToken()
+		if !p.parseFunctionParameter(lit) {
+			return false
+		}
 
-	for p.peekToken.Type == token.COMMA {
+		if p.peekToken.Type != token.COMMA {
+			break
+		}
 		// Move past comma
 		p.nextToken()
-		p.nextToken()
-
-		ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-		idents = append(idents, ident)
 	}
 
-	if !p.expectPeek(token.RPAREN) {
+	return p.expectPeek(end)
+}
+
+// parseLambdaLiteral parses the short function form `|a, b| body`, where body
+// is a block or a single expression whose value is returned.
+func (p *Parser) parseLambdaLiteral() ast.Expression {
+	lit := &ast.FunctionLiteral{Token: p.curToken}
+
+	if !p.parseFunctionParameters(lit, token.PIPE) {
 		return nil
 	}
+	p.nextToken()
+
+	lit.Body = p.parseLambdaBody(lit)
 
-	return idents
+	return lit
+}
+
+// parseArrowLambda parses the single parameter form `x => body`.
+func (p *Parser) parseArrowLambda() ast.Expression {
+	param := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	p.nextToken()
+	lit := &ast.FunctionLiteral{Token: p.curToken, Parameters: []*ast.Identifier{param}}
+	p.nextToken()
+
+	lit.Body = p.parseLambdaBody(lit)
+
+	return lit
+}
+
+func (p *Parser) parseLambdaBody(lit *ast.FunctionLiteral) *ast.BlockStatement {
+	loopDepth := p.loopDepth
+	p.loopDepth = 0
+	p.pushScope()
+	p.declareParameters(lit)
+	body := p.parseBlockOrExpression()
+	p.popScope()
+	p.loopDepth = loopDepth
+
+	return body
+}
+
+func (p *Parser) declareParameters(lit *ast.FunctionLiteral) {
+	for _, param := range lit.Parameters {
+		p.declare(param, false)
+	}
+	if lit.Rest != nil {
+		p.declare(lit.Rest, false)
+	}
+}
+
+// parseBlockOrExpression parses a `{` block, or a single expression wrapped
+// in a block of its own.
+func (p *Parser) parseBlockOrExpression() *ast.BlockStatement {
+	if p.curToken.Type == token.LSQUIG {
+		return p.parseBlockStatement()
+	}
+
+	stmt := &ast.ExpressionStatement{Token: p.curToken, Expression: p.parseExpression(LOWEST)}
+	return &ast.BlockStatement{Token: stmt.Token, Statements: []ast.Statement{stmt}}
+}
+
+// parsePipeExpression parses `x |> f(y)`, which calls f with x prepended to
+// its arguments.
+func (p *Parser) parsePipeExpression(left ast.Expression) ast.Expression {
+	exp := &ast.PipeExpression{Token: p.curToken, Left: left}
+
+	precedence := p.curPrecedence()
+	p.nextToken()
+	exp.Right = p.parseExpression(precedence)
+
+	return exp
+}
+
+// parseFunctionParameter parses one of `name`, `name = default` or `...rest`.
+func (p *Parser) parseFunctionParameter(lit *ast.FunctionLiteral) bool {
+	if lit.Rest != nil {
+		p.errors = append(p.errors, fmt.Errorf("%w: ...%s must be the last parameter", ErrInvalidParameter, lit.Rest))
+		return false
+	}
+
+	if p.curToken.Type == token.ELLIPSIS {
+		if !p.expectPeek(token.IDENT) {
+			return false
+		}
+		lit.Rest = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		return true
+	}
+
+	if p.curToken.Type != token.IDENT {
+		err := fmt.Errorf("%w: expected %q got %q", ErrUnexpectedToken, token.IDENT, p.curToken.Type)
+		p.errors = append(p.errors, err)
+		return false
+	}
+
+	ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	lit.Parameters = append(lit.Parameters, ident)
+
+	if p.peekToken.Type != token.ASSIGN {
+		if len(lit.Defaults) > 0 {
+			p.errors = append(p.errors, fmt.Errorf("%w: %s follows a parameter with a default value", ErrInvalidParameter, ident))
+			return false
+		}
+		return true
+	}
+
+	p.nextToken()
+	p.nextToken()
+
+	if lit.Defaults == nil {
+		lit.Defaults = make(map[string]ast.Expression)
+	}
+	lit.Defaults[ident.Value] = p.parseExpression(ASSIGN)
+
+	return true
 }
 
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	expression := &ast.CallExpression{Token: p.curToken, Function: function}
-	expression.Arguments = p.parseExpressionList(token.RPAREN)
+	if !p.parseCallArguments(expression) {
+		return nil
+	}
 	return expression
 }
 
-func (p *Parser) parseCallArguments() []ast.Expression {
-	args := []ast.Expression{}
+// parseCallArguments parses positional arguments followed by any keyword
+// arguments, written `name: value`.
+func (p *Parser) parseCallArguments(call *ast.CallExpression) bool {
+	call.Arguments = []ast.Expression{}
 
 	if p.peekToken.Type == token.RPAREN {
 		p.nextToken()
-		return args
+		return true
 	}
 
-	p.nextToken()
-	args = append(args, p.parseExpression(LOWEST))
-
-	for p.peekToken.Type == token.COMMA {
-		// skip past comma
-		p.nextToken()
+	seen := make(map[string]bool)
+	for {
 		p.nextToken()
 
-		args = append(args, p.parseExpression(LOWEST))
-	}
+		if p.curToken.Type == token.IDENT && p.peekToken.Type == token.COLON {
+			kw := &ast.KeywordArgument{Token: p.curToken}
+			kw.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			if seen[kw.Name.Value] {
+				p.errors = append(p.errors, fmt.Errorf("%w: duplicate keyword argument %s", ErrInvalidArgument, kw.Name))
+				return false
+			}
+			seen[kw.Name.Value] = true
+
+			p.nextToken()
+			p.nextToken()
+			kw.Value = p.parseExpression(LOWEST)
+			call.Keywords = append(call.Keywords, kw)
+		} else {
+			arg := p.parseExpression(LOWEST)
+			if len(call.Keywords) > 0 {
+				p.errors = append(p.errors, fmt.Errorf("%w: positional argument %s follows keyword arguments", ErrInvalidArgument, arg))
+				return false
+			}
+			call.Arguments = append(call.Arguments, arg)
+		}
 
-	if !p.expectPeek(token.RPAREN) {
-		return nil
+		if p.peekToken.Type != token.COMMA {
+			break
+		}
+		// skip past comma
+		p.nextToken()
 	}
 
-	return args
+	return p.expectPeek(token.RPAREN)
 }
 
 func (p *Parser) parseStringLiteral() ast.Expression {
 	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
 }
 
+func (p *Parser) parseInterpolatedString() ast.Expression {
+	str := &ast.InterpolatedString{Token: p.curToken, Head: p.curToken.Literal}
+
+	for {
+		p.nextToken()
+		if p.curToken.Type == token.FORMATSPEC || p.curToken.Type == token.INTERPMID || p.curToken.Type == token.INTERPEND {
+			p.errors = append(p.errors, fmt.Errorf("%s: %w", p.curToken.Pos, ErrEmptyInterpolation))
+			return nil
+		}
+
+		part := ast.Interpolation{Value: p.parseExpression(LOWEST)}
+		if p.peekToken.Type == token.FORMATSPEC {
+			p.nextToken()
+			part.Format = p.curToken.Literal
+		}
+
+		if p.peekToken.Type == token.INTERPMID {
+			p.nextToken()
+		} else if !p.expectPeek(token.INTERPEND) {
+			return nil
+		}
+		part.Text = p.curToken.Literal
+		str.Parts = append(str.Parts, part)
+
+		if p.curToken.Type == token.INTERPEND {
+			return str
+		}
+	}
+}
+
 func (p *Parser) parseArrayLiteral() ast.Expression {
 	array := &ast.ArrayLiteral{Token: p.curToken}
 
@@ -231,10 +480,22 @@ func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 }
 
 func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
-	exp := &ast.IndexExpression{Token: p.curToken, Left: left}
+	exp := &ast.IndexExpression{Token: p.curToken, Left: left, Optional: p.curToken.Type == token.OPTLSQUAR}
 
-	p.nextToken()
-	exp.Index = p.parseExpression(LOWEST)
+	if p.peekToken.Type != token.COLON {
+		p.nextToken()
+		exp.Index = p.parseExpression(LOWEST)
+	}
+
+	if p.peekToken.Type == token.COLON {
+		p.nextToken()
+		exp.Slice = true
+
+		if p.peekToken.Type != token.RSQUAR {
+			p.nextToken()
+			exp.End = p.parseExpression(LOWEST)
+		}
+	}
 
 	if !p.expectPeek(token.RSQUAR) {
 		return nil
@@ -243,8 +504,21 @@ func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	return exp
 }
 
+// parseDotExpression parses `left.name` or `left?.name`, a hash field or, when
+// called, a method of left.
+func (p *Parser) parseDotExpression(left ast.Expression) ast.Expression {
+	exp := &ast.DotExpression{Token: p.curToken, Left: left, Optional: p.curToken.Type == token.OPTDOT}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	exp.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	return exp
+}
+
 func (p *Parser) parseHashLiteral() ast.Expression {
-	hash := &ast.HashLiteral{Token: p.curToken, Pairs: make(map[ast.Expression]ast.Expression)}
+	hash := &ast.HashLiteral{Token: p.curToken, Pairs: []ast.HashPair{}}
 
 	for p.peekToken.Type != token.RSQUIG {
 		p.nextToken()
@@ -257,7 +531,7 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 
 		value := p.parseExpression(LOWEST)
 
-		hash.Pairs[key] = value
+		hash.Pairs = append(hash.Pairs, ast.HashPair{Key: key, Value: value})
 
 		if p.peekToken.Type != token.RSQUIG && !p.expectPeek(token.COMMA) {
 			return nil
@@ -270,3 +544,197 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 
 	return hash
 }
+
+func (p *Parser) parseAssignExpression(target ast.Expression) ast.Expression {
+	exp := &ast.AssignExpression{Token: p.curToken, Target: target}
+
+	switch target := target.(type) {
+	case *ast.Identifier:
+		p.checkAssign(target)
+	case *ast.DotExpression:
+		if target.Optional {
+			p.errors = append(p.errors, fmt.Errorf("%w: %s", ErrInvalidAssignTarget, target))
+			return nil
+		}
+	case *ast.IndexExpression:
+		if target.Slice || target.Optional {
+			p.errors = append(p.errors, fmt.Errorf("%w: %s", ErrInvalidAssignTarget, target))
+			return nil
+		}
+	default:
+		p.errors = append(p.errors, fmt.Errorf("%w: %s", ErrInvalidAssignTarget, target))
+		return nil
+	}
+
+	p.nextToken()
+
+	// Assignment is right associative, so `a = b[0] = 1` assigns both.
+	exp.Value = p.parseExpression(ASSIGN - 1)
+
+	return exp
+}
+
+func (p *Parser) parseRangeExpression(start ast.Expression) ast.Expression {
+	exp := &ast.RangeExpression{Token: p.curToken, Start: start}
+
+	precedence := p.curPrecedence()
+	p.nextToken()
+	exp.End = p.parseExpression(precedence)
+
+	// `step` is contextual rather than a keyword, so it stays usable as a name.
+	if p.peekToken.Type == token.IDENT && p.peekToken.Literal == "step" {
+		p.nextToken()
+		p.nextToken()
+		exp.Step = p.parseExpression(precedence)
+	}
+
+	return exp
+}
+
+func (p *Parser) parseSpreadExpression() ast.Expression {
+	exp := &ast.SpreadExpression{Token: p.curToken}
+
+	p.nextToken()
+	exp.Value = p.parseExpression(ASSIGN)
+
+	return exp
+}
+
+// parseTryExpression parses `try { ... }` followed by a catch clause, a
+// `finally { ... }` block, or both; one of them is required.
+func (p *Parser) parseTryExpression() ast.Expression {
+	exp := &ast.TryExpression{Token: p.curToken}
+
+	if !p.expectPeek(token.LSQUIG) {
+		return nil
+	}
+	exp.Block = p.parseBlockStatement()
+
+	if p.peekToken.Type == token.CATCH {
+		p.nextToken()
+		if !p.parseCatchClause(exp) {
+			return nil
+		}
+	}
+
+	if p.peekToken.Type == token.FINALLY {
+		p.nextToken()
+		if !p.expectPeek(token.LSQUIG) {
+			return nil
+		}
+		exp.Finally = p.parseBlockStatement()
+	}
+
+	if exp.Catch == nil && exp.Finally == nil {
+		p.errors = append(p.errors, fmt.Errorf("%w: %s", ErrTryWithoutHandler, exp))
+		return nil
+	}
+
+	return exp
+}
+
+// parseCatchClause parses `(e) { ... }` following `catch`, where the caught
+// error's name is only in scope within the block.
+func (p *Parser) parseCatchClause(exp *ast.TryExpression) bool {
+	p.pushScope()
+	defer p.popScope()
+
+	if p.peekToken.Type == token.LPAREN {
+		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return false
+		}
+		exp.Param = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		p.declare(exp.Param, false)
+		if !p.expectPeek(token.RPAREN) {
+			return false
+		}
+	}
+
+	if !p.expectPeek(token.LSQUIG) {
+		return false
+	}
+	exp.Catch = p.parseBlockStatement()
+
+	return true
+}
+
+// parseMatchExpression parses `match (subject) { pattern [if guard] => body,
+// ... }`, where each body is an expression or a block.
+func (p *Parser) parseMatchExpression() ast.Expression {
+	exp := &ast.MatchExpression{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	p.nextToken()
+	exp.Subject = p.parseExpression(LOWEST)
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LSQUIG) {
+		return nil
+	}
+
+	exhaustive := false
+	for p.peekToken.Type != token.RSQUIG {
+		p.nextToken()
+
+		arm := p.parseMatchArm()
+		if arm == nil {
+			return nil
+		}
+		exp.Arms = append(exp.Arms, arm)
+
+		if _, ok := arm.Pattern.(*ast.Identifier); ok && arm.Guard == nil {
+			exhaustive = true
+		}
+
+		if p.peekToken.Type != token.RSQUIG && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RSQUIG) {
+		return nil
+	}
+
+	if !exhaustive {
+		p.warnings = append(p.warnings, fmt.Errorf("%s: %w: %s", exp.Token.Pos, ErrNonExhaustiveMatch, exp.Subject))
+	}
+
+	return exp
+}
+
+func (p *Parser) parseMatchArm() *ast.MatchArm {
+	arm := &ast.MatchArm{}
+
+	noArrowLambda := p.noArrowLambda
+	p.noArrowLambda = true
+	defer func() { p.noArrowLambda = noArrowLambda }()
+
+	p.pushScope()
+	defer p.popScope()
+
+	if arm.Pattern = p.parsePattern(); arm.Pattern == nil {
+		return nil
+	}
+	p.declarePattern(arm.Pattern)
+
+	if p.peekToken.Type == token.IF {
+		p.nextToken()
+		p.nextToken()
+		arm.Guard = p.parseExpression(LOWEST)
+	}
+
+	if !p.expectPeek(token.FATARROW) {
+		return nil
+	}
+	p.nextToken()
+
+	p.noArrowLambda = noArrowLambda
+	arm.Body = p.parseBlockOrExpression()
+
+	return arm
+}
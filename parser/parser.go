@@ -17,13 +17,25 @@ type (
 type Parser struct {
 	l *lexer.Lexer
 
-	errors []error
+	errors   []error
+	warnings []error
 
 	curToken  token.Token
 	peekToken token.Token
 
 	prefixParseFns map[token.TokenType]prefixParseFunc
 	infixParseFns  map[token.TokenType]infixParseFunc
+
+	// loopDepth is the number of loops enclosing the current token within
+	// the current function body, used to reject a stray break or continue.
+	loopDepth int
+
+	// scope holds the names declared so far in the scope being parsed.
+	scope *scope
+
+	// noArrowLambda stops `x => ...` being read as a lambda while parsing a
+	// match arm's pattern and guard, where the arrow ends the arm's head.
+	noArrowLambda bool
 }
 
 func New(l *lexer.Lexer) *Parser {
@@ -33,32 +45,49 @@ func New(l *lexer.Lexer) *Parser {
 	}
 
 	p.prefixParseFns = map[token.TokenType]prefixParseFunc{
-		token.IDENT:    p.parseIdentifier,
-		token.INT:      p.parseIntegerLiteral,
-		token.MINUS:    p.parsePrefixExpression,
-		token.EXCLAIM:  p.parsePrefixExpression,
-		token.TRUE:     p.parseBoolean,
-		token.FALSE:    p.parseBoolean,
-		token.LPAREN:   p.parseGroupedExpression,
-		token.IF:       p.parseIfExpression,
-		token.FUNCTION: p.parseFunctionLiteral,
-		token.STRING:   p.parseStringLiteral,
-		token.LSQUAR:   p.parseArrayLiteral,
-		token.LSQUIG:   p.parseHashLiteral,
+		token.IDENT:       p.parseIdentifier,
+		token.INT:         p.parseIntegerLiteral,
+		token.MINUS:       p.parsePrefixExpression,
+		token.EXCLAIM:     p.parsePrefixExpression,
+		token.TRUE:        p.parseBoolean,
+		token.FALSE:       p.parseBoolean,
+		token.LPAREN:      p.parseGroupedExpression,
+		token.IF:          p.parseIfExpression,
+		token.FUNCTION:    p.parseFunctionLiteral,
+		token.STRING:      p.parseStringLiteral,
+		token.INTERPSTART: p.parseInterpolatedString,
+		token.LSQUAR:      p.parseArrayLiteral,
+		token.LSQUIG:      p.parseHashLiteral,
+		token.ELLIPSIS:    p.parseSpreadExpression,
+		token.MATCH:       p.parseMatchExpression,
+		token.PIPE:        p.parseLambdaLiteral,
+		token.NULL:        p.parseNullLiteral,
+		token.TRY:         p.parseTryExpression,
 	}
 	p.infixParseFns = map[token.TokenType]infixParseFunc{
-		token.EQ:       p.parseInfixExpression,
-		token.NEQ:      p.parseInfixExpression,
-		token.LT:       p.parseInfixExpression,
-		token.GT:       p.parseInfixExpression,
-		token.PLUS:     p.parseInfixExpression,
-		token.MINUS:    p.parseInfixExpression,
-		token.SLASH:    p.parseInfixExpression,
-		token.ASTERISK: p.parseInfixExpression,
-		token.LPAREN:   p.parseCallExpression,
-		token.LSQUAR:   p.parseIndexExpression,
+		token.EQ:        p.parseInfixExpression,
+		token.NEQ:       p.parseInfixExpression,
+		token.LT:        p.parseInfixExpression,
+		token.GT:        p.parseInfixExpression,
+		token.PLUS:      p.parseInfixExpression,
+		token.MINUS:     p.parseInfixExpression,
+		token.SLASH:     p.parseInfixExpression,
+		token.RATIO:     p.parseInfixExpression,
+		token.ASTERISK:  p.parseInfixExpression,
+		token.LPAREN:    p.parseCallExpression,
+		token.LSQUAR:    p.parseIndexExpression,
+		token.ASSIGN:    p.parseAssignExpression,
+		token.DOTDOT:    p.parseRangeExpression,
+		token.DOTDOTLT:  p.parseRangeExpression,
+		token.PIPELINE:  p.parsePipeExpression,
+		token.DOT:       p.parseDotExpression,
+		token.OPTDOT:    p.parseDotExpression,
+		token.OPTLSQUAR: p.parseIndexExpression,
+		token.COALESCE:  p.parseInfixExpression,
 	}
 
+	p.pushScope()
+
 	// Call twice to set both curToken and nextToken
 	p.nextToken()
 	p.nextToken()
@@ -69,6 +98,9 @@ func New(l *lexer.Lexer) *Parser {
 func (p *Parser) nextToken() {
 	p.curToken = p.peekToken
 	p.peekToken = p.l.NextToken()
+	if err := p.l.Err(); err != nil {
+		p.errors = append(p.errors, err)
+	}
 }
 
 func (p *Parser) ParseProgram() *ast.Program {
@@ -99,23 +131,93 @@ func (p *Parser) expectPeek(token token.TokenType) bool {
 
 func (p *Parser) parseStatement() ast.Statement {
 	switch p.curToken.Type {
-	case token.LET:
+	case token.LET, token.CONST:
 		return p.parseLetStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.THROW:
+		return p.parseThrowStatement()
+	case token.WHILE:
+		return p.parseWhileStatement()
+	case token.FOR:
+		return p.parseForStatement()
+	case token.FUNCTION:
+		if p.peekToken.Type == token.IDENT {
+			return p.parseFunctionStatement()
+		}
+		return p.parseExpressionStatement()
+	case token.BREAK, token.CONTINUE:
+		return p.parseLoopControlStatement()
+	case token.LSQUIG:
+		if p.isBlockStart() {
+			return p.parseStandaloneBlock()
+		}
+		return p.parseExpressionStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
 }
 
+// isBlockStart reports whether the `{` at the start of a statement opens a
+// block rather than a hash literal. A hash's first key is followed by a colon,
+// so the tokens are scanned up to the first colon or closing bracket outside
+// any nested brackets. An empty `{}` is a hash.
+func (p *Parser) isBlockStart() bool {
+	if p.peekToken.Type == token.RSQUIG {
+		return false
+	}
+
+	l := *p.l
+	depth := 0
+	for tok := p.peekToken; tok.Type != token.EOF; tok = l.NextToken() {
+		switch tok.Type {
+		case token.LPAREN, token.LSQUAR, token.LSQUIG, token.OPTLSQUAR:
+			depth++
+		case token.RPAREN, token.RSQUAR, token.RSQUIG:
+			if depth == 0 {
+				return true
+			}
+			depth--
+		case token.COLON:
+			if depth == 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// parseStandaloneBlock parses a `{ ... }` block used as a statement, to limit
+// the scope of the names declared within it.
+func (p *Parser) parseStandaloneBlock() ast.Statement {
+	block := p.parseBlockStatement()
+
+	if p.peekToken.Type == token.SEMICOLON {
+		p.nextToken()
+	}
+
+	return block
+}
+
 func (p *Parser) parseLetStatement() *ast.LetStatement {
 	stmt := &ast.LetStatement{Token: p.curToken}
 
-	if ok := p.expectPeek(token.IDENT); !ok {
-		return nil
-	}
+	if p.peekToken.Type == token.LSQUAR || p.peekToken.Type == token.LSQUIG {
+		p.nextToken()
+		if stmt.IsConst() {
+			p.errors = append(p.errors, fmt.Errorf("%w: const must bind a single name", ErrInvalidPattern))
+			return nil
+		}
+		if stmt.Pattern = p.parsePattern(); stmt.Pattern == nil {
+			return nil
+		}
+	} else {
+		if ok := p.expectPeek(token.IDENT); !ok {
+			return nil
+		}
 
-	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
 
 	if ok := p.expectPeek(token.ASSIGN); !ok {
 		return nil
@@ -123,6 +225,37 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 	p.nextToken()
 
 	stmt.Value = p.parseExpression(LOWEST)
+	if fn, ok := stmt.Value.(*ast.FunctionLiteral); ok && fn.Name == "" && stmt.Name != nil {
+		fn.Name = stmt.Name.Value
+	}
+
+	if stmt.Pattern != nil {
+		p.declarePattern(stmt.Pattern)
+	} else {
+		p.declare(stmt.Name, stmt.IsConst())
+	}
+
+	if p.peekToken.Type == token.SEMICOLON {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseFunctionStatement() ast.Statement {
+	stmt := &ast.FunctionStatement{Token: p.curToken}
+
+	p.nextToken()
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	p.declare(stmt.Name, false)
+
+	fn, ok := p.parseFunctionLiteral().(*ast.FunctionLiteral)
+	if !ok {
+		return nil
+	}
+	fn.Token = stmt.Token
+	fn.Name = stmt.Name.Value
+	stmt.Function = fn
 
 	if p.peekToken.Type == token.SEMICOLON {
 		p.nextToken()
@@ -144,6 +277,115 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	return stmt
 }
 
+func (p *Parser) parseThrowStatement() *ast.ThrowStatement {
+	stmt := &ast.ThrowStatement{Token: p.curToken}
+	p.nextToken()
+
+	stmt.Value = p.parseExpression(LOWEST)
+
+	if p.peekToken.Type == token.SEMICOLON {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseWhileStatement() ast.Statement {
+	stmt := &ast.WhileStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	p.nextToken()
+
+	stmt.Condition = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LSQUIG) {
+		return nil
+	}
+
+	p.loopDepth++
+	stmt.Body = p.parseBlockStatement()
+	p.loopDepth--
+
+	if p.peekToken.Type == token.SEMICOLON {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseForStatement() ast.Statement {
+	stmt := &ast.ForStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	stmt.Vars = append(stmt.Vars, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+
+	if p.peekToken.Type == token.COMMA {
+		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		stmt.Vars = append(stmt.Vars, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+	}
+
+	if !p.expectPeek(token.IN) {
+		return nil
+	}
+	p.nextToken()
+
+	stmt.Iterable = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LSQUIG) {
+		return nil
+	}
+
+	p.pushScope()
+	for _, v := range stmt.Vars {
+		p.declare(v, false)
+	}
+	p.loopDepth++
+	stmt.Body = p.parseBlockStatement()
+	p.loopDepth--
+	p.popScope()
+
+	if p.peekToken.Type == token.SEMICOLON {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseLoopControlStatement() ast.Statement {
+	tok := p.curToken
+	if p.loopDepth == 0 {
+		p.errors = append(p.errors, fmt.Errorf("%w: %s", ErrLoopControlOutsideLoop, tok.Literal))
+	}
+
+	if p.peekToken.Type == token.SEMICOLON {
+		p.nextToken()
+	}
+
+	if tok.Type == token.BREAK {
+		return &ast.BreakStatement{Token: tok}
+	}
+	return &ast.ContinueStatement{Token: tok}
+}
+
 func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 	stmt := &ast.ExpressionStatement{Token: p.curToken}
 
@@ -182,3 +424,9 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 func (p *Parser) Errors() error {
 	return errors.Join(p.errors...)
 }
+
+// Warnings reports problems which do not stop the program from running, such
+// as a match expression without a wildcard arm.
+func (p *Parser) Warnings() error {
+	return errors.Join(p.warnings...)
+}
@@ -1,7 +1,9 @@
 package parser_test
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"testing"
 
 	"git.tigh.dev/tigh-latte/monkeyscript/ast"
@@ -74,6 +76,284 @@ func TestLetStatements(t *testing.T) {
 	}
 }
 
+func TestConstStatements(t *testing.T) {
+	p := parser.New(lexer.New("const TAX_RATE = 20;"))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.LetStatement)
+	if !ok {
+		t.Fatalf("stmt not *ast.LetStatement. got=%T", program.Statements[0])
+	}
+	if !stmt.IsConst() {
+		t.Errorf("stmt.IsConst() is false")
+	}
+	if !testLiteralExpression(t, stmt.Value, 20) {
+		return
+	}
+	if stmt.String() != "const TAX_RATE = 20;" {
+		t.Errorf("stmt.String() wrong. got=%q", stmt.String())
+	}
+}
+
+func TestConstViolations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected error
+	}{
+		{"const X = 1; X = 2;", parser.ErrConstAssign},
+		{"const X = 1; let f = fn() { X = 2; };", parser.ErrConstAssign},
+		{"const X = 1; for (i in 0..2) { X = i; }", parser.ErrConstAssign},
+		{"const X = 1; let X = 2;", parser.ErrConstRedeclared},
+		{"const X = 1; const X = 2;", parser.ErrConstRedeclared},
+		{"let X = 1; const X = 2;", parser.ErrConstRedeclared},
+		{"const X = 1; fn X() {}", parser.ErrConstRedeclared},
+		{"const X = 1; let [X] = [2];", parser.ErrConstRedeclared},
+		{"const [a, b] = c;", parser.ErrInvalidPattern},
+		{"const X = 1; let f = fn(X) { X = 2; };", nil},
+		{"const X = 1; let f = fn() { let X = 2; X = 3; };", nil},
+		{"const X = 1; let f = |X| X = 2;", nil},
+		{"const X = 1; for (X in 0..2) { X = 2; }", nil},
+		{"const X = 1; match (2) { X => X = 3 }", nil},
+		{"let x = 1; let x = 2; x = 3;", nil},
+		{"const X = 1; { X = 2; }", parser.ErrConstAssign},
+		{"const X = 1; if (true) { X = 2; }", parser.ErrConstAssign},
+		{"{ const X = 1; } X = 2;", nil},
+		{"if (true) { const X = 1; } else { const X = 2; } X = 3;", nil},
+		{"const X = 1; { const X = 2; } X;", nil},
+		{"const X = 1; if (true) { let X = 2; X = 3; }", nil},
+		{"const X = 1; while (true) { const X = 2; }", nil},
+		{"let f = fn(x) { const x = 1; };", nil},
+		{"{ const X = 1; let X = 2; }", parser.ErrConstRedeclared},
+	}
+
+	for _, test := range tests {
+		p := parser.New(lexer.New(test.input))
+		p.ParseProgram()
+
+		err := p.Errors()
+		if test.expected == nil {
+			if err != nil {
+				t.Errorf("unexpected error for %q: %v", test.input, err)
+			}
+			continue
+		}
+		if !errors.Is(err, test.expected) {
+			t.Errorf("expected %q error for %q. got=%v", test.expected, test.input, err)
+		}
+	}
+}
+
+func TestTryExpression(t *testing.T) {
+	tests := []struct {
+		input      string
+		expected   string
+		param      string
+		hasCatch   bool
+		hasFinally bool
+	}{
+		{"try { f(x) } catch (e) { g(e) }", "try { f(x) } catch (e) { g(e) }", "e", true, false},
+		{"try { f(x) } catch { 0 }", "try { f(x) } catch { 0 }", "", true, false},
+		{"try { f(x) } finally { g() }", "try { f(x) } finally { g() }", "", false, true},
+		{"try { f(x) } catch (err) { throw err; } finally { g() }", "try { f(x) } catch (err) { throw err; } finally { g() }", "err", true, true},
+	}
+
+	for _, test := range tests {
+		p := parser.New(lexer.New(test.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		exp, ok := stmt.Expression.(*ast.TryExpression)
+		if !ok {
+			t.Fatalf("stmt.Expression not *ast.TryExpression. got=%T", stmt.Expression)
+		}
+		if exp.String() != test.expected {
+			t.Errorf("exp.String() wrong. want=%q, got=%q", test.expected, exp.String())
+		}
+		if (exp.Catch != nil) != test.hasCatch {
+			t.Errorf("wrong catch clause for %q. got=%v", test.input, exp.Catch)
+		}
+		if (exp.Finally != nil) != test.hasFinally {
+			t.Errorf("wrong finally clause for %q. got=%v", test.input, exp.Finally)
+		}
+		if test.param == "" {
+			if exp.Param != nil {
+				t.Errorf("unexpected catch parameter %s", exp.Param)
+			}
+		} else if !testIdentifier(t, exp.Param, test.param) {
+			return
+		}
+	}
+}
+
+func TestInvalidTryExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected error
+	}{
+		{"try { f(x) }", parser.ErrTryWithoutHandler},
+		{"try f(x) catch { 0 }", parser.ErrUnexpectedToken},
+		{"try { f(x) } catch (1) { 0 }", parser.ErrUnexpectedToken},
+		{"try { f(x) } catch (e) { 0 } finally 1", parser.ErrUnexpectedToken},
+	}
+
+	for _, test := range tests {
+		p := parser.New(lexer.New(test.input))
+		p.ParseProgram()
+
+		if err := p.Errors(); !errors.Is(err, test.expected) {
+			t.Errorf("expected %q error for %q. got=%v", test.expected, test.input, err)
+		}
+	}
+}
+
+func TestMatchExpression(t *testing.T) {
+	input := `match (txn) {
+		0 => "zero",
+		1..<100 => "small",
+		n: integer if n < 0 => { "negative" },
+		[first, ...rest] => first,
+		{amount, currency: "GBP"} => amount,
+		_ => "other"
+	}`
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if p.Warnings() != nil {
+		t.Errorf("unexpected warnings: %v", p.Warnings())
+	}
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.MatchExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.MatchExpression. got=%T", stmt.Expression)
+	}
+
+	if !testIdentifier(t, exp.Subject, "txn") {
+		return
+	}
+	if len(exp.Arms) != 6 {
+		t.Fatalf("wrong number of arms. want=6, got=%d", len(exp.Arms))
+	}
+
+	tests := []struct {
+		pattern any
+		guard   string
+	}{
+		{&ast.LiteralPattern{}, ""},
+		{&ast.LiteralPattern{}, ""},
+		{&ast.TypePattern{}, "(n < 0)"},
+		{&ast.ArrayPattern{}, ""},
+		{&ast.HashPattern{}, ""},
+		{&ast.Identifier{}, ""},
+	}
+	for i, test := range tests {
+		arm := exp.Arms[i]
+		if fmt.Sprintf("%T", arm.Pattern) != fmt.Sprintf("%T", test.pattern) {
+			t.Errorf("arms[%d] pattern wrong type. want=%T, got=%T", i, test.pattern, arm.Pattern)
+		}
+		if test.guard != "" && (arm.Guard == nil || arm.Guard.String() != test.guard) {
+			t.Errorf("arms[%d] guard wrong. want=%q, got=%v", i, test.guard, arm.Guard)
+		}
+	}
+
+	expected := `match (txn) { 0 => zero, (1..<100) => small, n: integer if (n < 0) => negative, ` +
+		`[first, ...rest] => first, {amount, currency: GBP} => amount, _ => other }`
+	if exp.String() != expected {
+		t.Errorf("exp.String() wrong. want=%q, got=%q", expected, exp.String())
+	}
+}
+
+func TestMatchExhaustivenessWarning(t *testing.T) {
+	tests := []struct {
+		input string
+		warns bool
+	}{
+		{"match (x) { 1 => 2 }", true},
+		{"match (x) { n if n > 1 => 2 }", true},
+		{"match (x) { 1 => 2, _ => 3 }", false},
+		{"match (x) { 1 => 2, n => n }", false},
+	}
+
+	for _, test := range tests {
+		p := parser.New(lexer.New(test.input))
+		p.ParseProgram()
+		checkParserErrors(t, p)
+
+		warned := errors.Is(p.Warnings(), parser.ErrNonExhaustiveMatch)
+		if warned != test.warns {
+			t.Errorf("wrong warning for %q. want=%t, got=%v", test.input, test.warns, p.Warnings())
+		}
+	}
+}
+
+func TestDestructuringLetStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{{
+		input:    "let [head, ...tail] = arr;",
+		expected: "let [head, ...tail] = arr;",
+	}, {
+		input:    "let {amount, currency} = txn;",
+		expected: "let {amount, currency} = txn;",
+	}, {
+		input:    `let {amount: amt, currency = "USD", "first name": name} = txn;`,
+		expected: "let {amount: amt, currency = USD, first name: name} = txn;",
+	}, {
+		input:    "let [a, [b, c] = [1, 2], {d: {e}}] = xs;",
+		expected: "let [a, [b, c] = [1, 2], {d: {e}}] = xs;",
+	}, {
+		input:    "let {id, ...others} = row;",
+		expected: "let {id, ...others} = row;",
+	}, {
+		input:    "let [] = [];",
+		expected: "let [] = [];",
+	}}
+
+	for _, test := range tests {
+		l := lexer.New(test.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt, ok := program.Statements[0].(*ast.LetStatement)
+		if !ok {
+			t.Fatalf("stmt not *ast.LetStatement. got=%T", program.Statements[0])
+		}
+		if stmt.Pattern == nil {
+			t.Fatalf("stmt.Pattern is nil")
+		}
+
+		if stmt.String() != test.expected {
+			t.Errorf("stmt.String() wrong. want=%q, got=%q", test.expected, stmt.String())
+		}
+	}
+}
+
+func TestInvalidPatterns(t *testing.T) {
+	tests := []string{
+		"let [...a, b] = c;",
+		"let [if] = c;",
+		"let {1: a} = c;",
+		`let {"a"} = c;`,
+		"let {...a, b} = c;",
+	}
+
+	for _, input := range tests {
+		p := parser.New(lexer.New(input))
+		p.ParseProgram()
+
+		if err := p.Errors(); err == nil {
+			t.Errorf("expected parser error for %q", input)
+		}
+	}
+}
+
 func TestReturnStatement(t *testing.T) {
 	tests := []struct {
 		input  string
@@ -169,6 +449,115 @@ func TestIntegerExpression(t *testing.T) {
 	}
 }
 
+func TestBigIntegerExpression(t *testing.T) {
+	input := "92233720368547758070;"
+
+	p := parser.New(lexer.New(input))
+	prog := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := prog.Statements[0].(*ast.ExpressionStatement)
+	literal, ok := stmt.Expression.(*ast.IntegerLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.IntegerLiteral. got=%T", stmt.Expression)
+	}
+	if literal.Big == nil || literal.Big.String() != "92233720368547758070" {
+		t.Errorf("literal.Big not %s. got=%v", "92233720368547758070", literal.Big)
+	}
+	if literal.String() != "92233720368547758070" {
+		t.Errorf("literal.String() wrong. got=%s", literal.String())
+	}
+}
+
+func TestIntegerLiteralForms(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"1_000_000", 1000000},
+		{"0xFF", 255},
+		{"0x7fff_ffff_ffff_ffff", 9223372036854775807},
+		{"0b1010_1010", 170},
+		{"0o755", 493},
+		{"0755", 755},
+		{"1e3", 1000},
+		{"1_5E+2", 1500},
+		{"200e-2", 2},
+		{"-9223372036854775808", math.MinInt64},
+	}
+
+	for _, test := range tests {
+		p := parser.New(lexer.New(test.input))
+		prog := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := prog.Statements[0].(*ast.ExpressionStatement)
+		literal, ok := stmt.Expression.(*ast.IntegerLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.IntegerLiteral. got=%T", stmt.Expression)
+		}
+		if literal.Value != test.expected || literal.Big != nil {
+			t.Errorf("%q - literal.Value not %d. got=%d", test.input, test.expected, literal.Value)
+		}
+		if literal.String() != test.input {
+			t.Errorf("literal.String() not %q. got=%q", test.input, literal.String())
+		}
+	}
+}
+
+func TestRationalLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"25e-2", "1/4"},
+		{"1_5e-1", "3/2"},
+		{"1E-3", "1/1000"},
+	}
+
+	for _, test := range tests {
+		p := parser.New(lexer.New(test.input))
+		prog := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := prog.Statements[0].(*ast.ExpressionStatement)
+		literal, ok := stmt.Expression.(*ast.RationalLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.RationalLiteral. got=%T", stmt.Expression)
+		}
+		if literal.Value.String() != test.expected {
+			t.Errorf("%q - literal.Value not %s. got=%s", test.input, test.expected, literal.Value)
+		}
+		if literal.String() != test.input {
+			t.Errorf("literal.String() not %q. got=%q", test.input, literal.String())
+		}
+	}
+}
+
+func TestMalformedIntegerLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x = 0x;", `1:9: malformed number literal "0x": missing hexadecimal digits`},
+		{"f(1,\n  2__0)", `2:3: malformed number literal "2__0": '_' must separate digits`},
+		{"1e + 1", `1:1: malformed number literal "1e": missing exponent digits`},
+	}
+
+	for _, test := range tests {
+		p := parser.New(lexer.New(test.input))
+		p.ParseProgram()
+
+		err := p.Errors()
+		if !errors.Is(err, lexer.ErrMalformedNumber) {
+			t.Fatalf("%q - expected ErrMalformedNumber. got=%v", test.input, err)
+		}
+		if err.Error() != test.expected {
+			t.Errorf("%q - error wrong. expected=%q, got=%q", test.input, test.expected, err.Error())
+		}
+	}
+}
+
 func TestParsingPrefixExpressions(t *testing.T) {
 	tests := []struct {
 		input       string
@@ -449,6 +838,96 @@ func TestOperatorPrecendenceParsing(t *testing.T) {
 	}, {
 		input:    "add(a * b[2], b[1], 2 * [1, 2][1])",
 		expected: "add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
+	}, {
+		input:    "a[0] = b[1] = 1 + 2",
+		expected: "((a[0]) = ((b[1]) = (1 + 2)))",
+	}, {
+		input:    "a[i] = a[i] * 2 == 4",
+		expected: "((a[i]) = (((a[i]) * 2) == 4))",
+	}, {
+		input:    "0..n + 1",
+		expected: "(0..(n + 1))",
+	}, {
+		input:    "fee * 7 // 30 + 1",
+		expected: "(((fee * 7) // 30) + 1)",
+	}, {
+		input:    "a < 1..2",
+		expected: "(a < (1..2))",
+	}, {
+		input:    "0..<n step 1 + 1",
+		expected: "(0..<n step (1 + 1))",
+	}, {
+		input:    "a[1:n - 1]",
+		expected: "(a[1:(n - 1)])",
+	}, {
+		input:    "a[:-1] + b[2:]",
+		expected: "((a[:(-1)]) + (b[2:]))",
+	}, {
+		input:    "a[:]",
+		expected: "(a[:])",
+	}, {
+		input:    "a[0..<2]",
+		expected: "(a[(0..<2)])",
+	}, {
+		input:    "let step = 2; 0..10 step step",
+		expected: "let step = 2;(0..10 step step)",
+	}, {
+		input:    "f(...a, b, ...c + d)",
+		expected: "f(...a, b, ...(c + d))",
+	}, {
+		input:    "[...a, ...0..n]",
+		expected: "[...a, ...(0..n)]",
+	}, {
+		input:    "a |> f(b) |> g",
+		expected: "((a |> f(b)) |> g)",
+	}, {
+		input:    "a + b |> f() == c",
+		expected: "(((a + b) |> f()) == c)",
+	}, {
+		input:    "0..n |> len",
+		expected: "((0..n) |> len)",
+	}, {
+		input:    "map(xs, |x| x * 2)",
+		expected: "map(xs, |x| (x * 2))",
+	}, {
+		input:    "map(xs, x => x + 1)",
+		expected: "map(xs, |x| (x + 1))",
+	}, {
+		input:    "xs |> reduce(0, |acc, x| acc + x)",
+		expected: "(xs |> reduce(0, |acc, x| (acc + x)))",
+	}, {
+		input:    "let f = |a, b = 1, ...c| { a };",
+		expected: "let f = |a, b = 1, ...c| a;",
+	}, {
+		input:    "txn.amount * 2",
+		expected: "(txn.amount * 2)",
+	}, {
+		input:    "-a.b",
+		expected: "(-a.b)",
+	}, {
+		input:    "a.b.c(d).e[0]",
+		expected: "(a.b.c(d).e[0])",
+	}, {
+		input:    "a.b = c.d = 1",
+		expected: "(a.b = (c.d = 1))",
+	}, {
+		input:    "rows |> a.b(1)",
+		expected: "(rows |> a.b(1))",
+	}, {
+		input:    "a?.b.c?[0]",
+		expected: "(a?.b.c?[0])",
+	}, {
+		input:    "a ?? b ?? c",
+		expected: "((a ?? b) ?? c)",
+	}, {
+		input:    "a ?? b == c",
+		expected: "(a ?? (b == c))",
+	}, {
+		input:    "x = a?.b ?? null",
+		expected: "(x = (a?.b ?? null))",
+	}, {
+		input:    `{"z": 1, "a": 2 + 3, "m": {"y": 4, "b": 5}}`,
+		expected: "{z:1, a:(2 + 3), m:{y:4, b:5}}",
 	}}
 
 	for _, test := range tests {
@@ -654,6 +1133,85 @@ func TestFunctionLiteralParsing(t *testing.T) {
 	testInfixExpression(t, bodyStmt.Expression, "x", "+", "y")
 }
 
+func TestLambdaLiteralParsing(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedParams []string
+	}{
+		{input: "|| 1", expectedParams: []string{}},
+		{input: "|x| x * 2", expectedParams: []string{"x"}},
+		{input: "|x, y| { x + y }", expectedParams: []string{"x", "y"}},
+		{input: "x => x * 2", expectedParams: []string{"x"}},
+	}
+
+	for _, test := range tests {
+		p := parser.New(lexer.New(test.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		function, ok := stmt.Expression.(*ast.FunctionLiteral)
+		if !ok {
+			t.Fatalf("stmt.Expression is not ast.FunctionLiteral. got=%T", stmt.Expression)
+		}
+
+		if len(function.Parameters) != len(test.expectedParams) {
+			t.Errorf("length parameters wrong. want %d, got=%d\n",
+				len(test.expectedParams), len(function.Parameters))
+		}
+
+		for i, ident := range test.expectedParams {
+			testLiteralExpression(t, function.Parameters[i], ident)
+		}
+
+		if len(function.Body.Statements) != 1 {
+			t.Errorf("function.Body.Statements has not 1 statements. got=%d\n",
+				len(function.Body.Statements))
+		}
+	}
+}
+
+func TestFunctionStatementParsing(t *testing.T) {
+	input := `fn add(x, y) { x + y; }`
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.FunctionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.FunctionStatement. got=%T", program.Statements[0])
+	}
+
+	if !testIdentifier(t, stmt.Name, "add") {
+		return
+	}
+	if stmt.Function.Name != "add" {
+		t.Errorf("stmt.Function.Name not %q. got=%q", "add", stmt.Function.Name)
+	}
+	if len(stmt.Function.Parameters) != 2 {
+		t.Fatalf("function literal parameters wrong. want 2, got=%d", len(stmt.Function.Parameters))
+	}
+	if stmt.String() != "fn add(x, y) {(x + y)}" {
+		t.Errorf("stmt.String() wrong. got=%q", stmt.String())
+	}
+}
+
+func TestLetNamesFunctionLiteral(t *testing.T) {
+	program := parser.New(lexer.New(`let double = fn(x) { x * 2 };`)).ParseProgram()
+
+	stmt := program.Statements[0].(*ast.LetStatement)
+	fn, ok := stmt.Value.(*ast.FunctionLiteral)
+	if !ok {
+		t.Fatalf("stmt.Value is not ast.FunctionLiteral. got=%T", stmt.Value)
+	}
+
+	if fn.Name != "double" {
+		t.Errorf("fn.Name not %q. got=%q", "double", fn.Name)
+	}
+}
+
 func TestFunctionParameterParsing(t *testing.T) {
 	tests := []struct {
 		input          string
@@ -683,6 +1241,61 @@ func TestFunctionParameterParsing(t *testing.T) {
 	}
 }
 
+func TestFunctionDefaultAndRestParameterParsing(t *testing.T) {
+	input := `fn(amount, rounding = "half_even", places = amount + 1, ...rest) { amount }`
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	function, ok := stmt.Expression.(*ast.FunctionLiteral)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.FunctionLiteral. got=%T", stmt.Expression)
+	}
+
+	if len(function.Parameters) != 3 {
+		t.Fatalf("function literal parameters wrong. want 3, got=%d", len(function.Parameters))
+	}
+	if len(function.Defaults) != 2 {
+		t.Fatalf("function literal defaults wrong. want 2, got=%d", len(function.Defaults))
+	}
+	if _, ok := function.Defaults["amount"]; ok {
+		t.Errorf("parameter amount should not have a default")
+	}
+	if def := function.Defaults["rounding"].String(); def != "half_even" {
+		t.Errorf("default for rounding wrong. got=%q", def)
+	}
+	testInfixExpression(t, function.Defaults["places"], "amount", "+", 1)
+
+	if function.Rest == nil || !testIdentifier(t, function.Rest, "rest") {
+		t.Fatalf("function.Rest not parsed. got=%v", function.Rest)
+	}
+
+	expected := "fn(amount, rounding = half_even, places = (amount + 1), ...rest) {amount}"
+	if function.String() != expected {
+		t.Errorf("function.String() wrong. want=%q, got=%q", expected, function.String())
+	}
+}
+
+func TestInvalidFunctionParameters(t *testing.T) {
+	tests := []string{
+		"fn(...rest, a) {}",
+		"fn(...a, ...b) {}",
+		"fn(a = 1, b) {}",
+	}
+
+	for _, input := range tests {
+		p := parser.New(lexer.New(input))
+		p.ParseProgram()
+
+		if err := p.Errors(); !errors.Is(err, parser.ErrInvalidParameter) {
+			t.Errorf("expected %q error for %q. got=%v", parser.ErrInvalidParameter, input, err)
+		}
+	}
+}
+
 func TestCallExpressionParsing(t *testing.T) {
 	input := "add(1, 2 * 3, 4 + 5);"
 
@@ -773,6 +1386,55 @@ func TestCallExpressionParameterParsing(t *testing.T) {
 	}
 }
 
+func TestCallExpressionKeywordArgumentParsing(t *testing.T) {
+	input := "amortize(principal, 5, periods: 12, balloon: 1 + 1)"
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.CallExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.CallExpression. got=%T", stmt.Expression)
+	}
+
+	if len(exp.Arguments) != 2 {
+		t.Fatalf("wrong length of arguments. got=%d", len(exp.Arguments))
+	}
+	testIdentifier(t, exp.Arguments[0], "principal")
+	testIntegerLiteral(t, exp.Arguments[1], 5)
+
+	if len(exp.Keywords) != 2 {
+		t.Fatalf("wrong length of keyword arguments. got=%d", len(exp.Keywords))
+	}
+	testIdentifier(t, exp.Keywords[0].Name, "periods")
+	testIntegerLiteral(t, exp.Keywords[0].Value, 12)
+	testIdentifier(t, exp.Keywords[1].Name, "balloon")
+	testInfixExpression(t, exp.Keywords[1].Value, 1, "+", 1)
+
+	if exp.String() != "amortize(principal, 5, periods: 12, balloon: (1 + 1))" {
+		t.Errorf("exp.String() wrong. got=%q", exp.String())
+	}
+}
+
+func TestInvalidCallArguments(t *testing.T) {
+	tests := []string{
+		"f(a: 1, 2)",
+		"f(a: 1, a: 2)",
+	}
+
+	for _, input := range tests {
+		p := parser.New(lexer.New(input))
+		p.ParseProgram()
+
+		if err := p.Errors(); !errors.Is(err, parser.ErrInvalidArgument) {
+			t.Errorf("expected %q error for %q. got=%v", parser.ErrInvalidArgument, input, err)
+		}
+	}
+}
+
 func TestStringLiteralExpression(t *testing.T) {
 	input := `"hello world";`
 
@@ -792,6 +1454,74 @@ func TestStringLiteralExpression(t *testing.T) {
 	}
 }
 
+func TestInterpolatedStringParsing(t *testing.T) {
+	input := `"Total: ${total * 2:.2f} for ${customer.name}!"`
+
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	str, ok := stmt.Expression.(*ast.InterpolatedString)
+	if !ok {
+		t.Fatalf("exp not *ast.InterpolatedString. got=%T", stmt.Expression)
+	}
+
+	if str.Head != "Total: " {
+		t.Errorf("str.Head not %q. got=%q", "Total: ", str.Head)
+	}
+	if len(str.Parts) != 2 {
+		t.Fatalf("str.Parts does not contain 2 parts. got=%d", len(str.Parts))
+	}
+
+	tests := []struct {
+		value  string
+		format string
+		text   string
+	}{
+		{"(total * 2)", ".2f", " for "},
+		{"customer.name", "", "!"},
+	}
+
+	for i, test := range tests {
+		part := str.Parts[i]
+		if part.Value.String() != test.value {
+			t.Errorf("parts[%d] - value wrong. expected=%q, got=%q", i, test.value, part.Value.String())
+		}
+		if part.Format != test.format {
+			t.Errorf("parts[%d] - format wrong. expected=%q, got=%q", i, test.format, part.Format)
+		}
+		if part.Text != test.text {
+			t.Errorf("parts[%d] - text wrong. expected=%q, got=%q", i, test.text, part.Text)
+		}
+	}
+
+	if str.String() != `"Total: ${(total * 2):.2f} for ${customer.name}!"` {
+		t.Errorf("str.String() wrong. got=%s", str.String())
+	}
+}
+
+func TestInvalidInterpolatedStrings(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected error
+	}{
+		{`"a${}b"`, parser.ErrEmptyInterpolation},
+		{`"a${:d}b"`, parser.ErrEmptyInterpolation},
+		{`"a${x y}b"`, parser.ErrUnexpectedToken},
+		{`"a${x`, parser.ErrUnexpectedToken},
+	}
+
+	for _, test := range tests {
+		p := parser.New(lexer.New(test.input))
+		p.ParseProgram()
+
+		if err := p.Errors(); !errors.Is(err, test.expected) {
+			t.Errorf("expected %q error for %q. got=%v", test.expected, test.input, err)
+		}
+	}
+}
+
 func TestParsingArrayLiterals(t *testing.T) {
 	input := "[1, 2* 2, 3 + 3]"
 
@@ -845,6 +1575,178 @@ func TestParsingIndexExpressions(t *testing.T) {
 	}
 }
 
+func TestParsingAssignExpressions(t *testing.T) {
+	input := `totals["food"] = 5`
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("exp not ast.ExpressionsStatement, got=%T", program.Statements[0])
+	}
+
+	assign, ok := stmt.Expression.(*ast.AssignExpression)
+	if !ok {
+		t.Fatalf("exp not ast.AssignExpression, got=%T", stmt.Expression)
+	}
+
+	target, ok := assign.Target.(*ast.IndexExpression)
+	if !ok {
+		t.Fatalf("assign.Target not ast.IndexExpression, got=%T", assign.Target)
+	}
+
+	if !testIdentifier(t, target.Left, "totals") {
+		return
+	}
+	if target.Index.String() != "food" {
+		t.Errorf("target.Index not %q. got=%q", "food", target.Index.String())
+	}
+	testIntegerLiteral(t, assign.Value, 5)
+}
+
+func TestParsingInvalidAssignTarget(t *testing.T) {
+	tests := []string{
+		"1 + 2 = 3",
+		"a[1:2] = [3]",
+		"a?.b = 1",
+		"a?[0] = 1",
+	}
+
+	for _, input := range tests {
+		p := parser.New(lexer.New(input))
+		p.ParseProgram()
+
+		if err := p.Errors(); !errors.Is(err, parser.ErrInvalidAssignTarget) {
+			t.Errorf("expected %q error for %q. got=%v", parser.ErrInvalidAssignTarget, input, err)
+		}
+	}
+}
+
+func TestWhileStatement(t *testing.T) {
+	input := `while (x < 10) { x = x + 1; if (x == 5) { continue; } break; }`
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.WhileStatement)
+	if !ok {
+		t.Fatalf("stmt not ast.WhileStatement. got=%T", program.Statements[0])
+	}
+
+	if !testInfixExpression(t, stmt.Condition, "x", "<", 10) {
+		return
+	}
+
+	if len(stmt.Body.Statements) != 3 {
+		t.Fatalf("body does not contain 3 statements. got=%d", len(stmt.Body.Statements))
+	}
+
+	if _, ok := stmt.Body.Statements[2].(*ast.BreakStatement); !ok {
+		t.Errorf("stmt.Body.Statements[2] not ast.BreakStatement. got=%T", stmt.Body.Statements[2])
+	}
+}
+
+func TestStandaloneBlockStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		isBlock  bool
+	}{
+		{"{ let x = 1; x }", "{ let x = 1;x }", true},
+		{"{ x = 1 };", "{ (x = 1) }", true},
+		{"{ f(a: 1) }", "{ f(a: 1) }", true},
+		{"{ { let y = [1]; } }", "{ { let y = [1]; } }", true},
+		{"{}", "{}", false},
+		{`{"a": 1}`, "{a:1}", false},
+		{`{[1, 2]: {"b": 2}}["a"]`, "({[1, 2]:{b:2}}[a])", false},
+	}
+
+	for _, test := range tests {
+		p := parser.New(lexer.New(test.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement for %q. got=%d", test.input, len(program.Statements))
+		}
+		if _, ok := program.Statements[0].(*ast.BlockStatement); ok != test.isBlock {
+			t.Errorf("wrong statement for %q. got=%T", test.input, program.Statements[0])
+		}
+		if program.String() != test.expected {
+			t.Errorf("expected=%q, got=%q", test.expected, program.String())
+		}
+	}
+}
+
+func TestForStatement(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedVars []string
+		expectedIter string
+	}{{
+		input:        "for (x in xs) { x }",
+		expectedVars: []string{"x"},
+		expectedIter: "xs",
+	}, {
+		input:        "for (k, v in totals) { break; }",
+		expectedVars: []string{"k", "v"},
+		expectedIter: "totals",
+	}, {
+		input:        "for (i in 0..len(xs)) { continue; }",
+		expectedVars: []string{"i"},
+		expectedIter: "(0..len(xs))",
+	}}
+
+	for _, test := range tests {
+		l := lexer.New(test.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt, ok := program.Statements[0].(*ast.ForStatement)
+		if !ok {
+			t.Fatalf("stmt not ast.ForStatement. got=%T", program.Statements[0])
+		}
+
+		if len(stmt.Vars) != len(test.expectedVars) {
+			t.Fatalf("wrong number of loop variables. want=%d, got=%d", len(test.expectedVars), len(stmt.Vars))
+		}
+		for i, v := range test.expectedVars {
+			testIdentifier(t, stmt.Vars[i], v)
+		}
+
+		if stmt.Iterable.String() != test.expectedIter {
+			t.Errorf("stmt.Iterable not %q. got=%q", test.expectedIter, stmt.Iterable.String())
+		}
+	}
+}
+
+func TestLoopControlOutsideLoop(t *testing.T) {
+	tests := []string{
+		"break;",
+		"if (true) { continue; }",
+		"while (true) { let f = fn() { break; }; }",
+	}
+
+	for _, input := range tests {
+		p := parser.New(lexer.New(input))
+		p.ParseProgram()
+
+		if err := p.Errors(); !errors.Is(err, parser.ErrLoopControlOutsideLoop) {
+			t.Errorf("expected %q error for %q. got=%v", parser.ErrLoopControlOutsideLoop, input, err)
+		}
+	}
+}
+
 func TestParsingHashLiteralsStringKeys(t *testing.T) {
 	input := `{"one": 1, "two": 2, "three": 3}`
 
@@ -872,7 +1774,8 @@ func TestParsingHashLiteralsStringKeys(t *testing.T) {
 		"three": 3,
 	}
 
-	for k, v := range hash.Pairs {
+	for _, pair := range hash.Pairs {
+		k, v := pair.Key, pair.Value
 		literal, ok := k.(*ast.StringLiteral)
 		if !ok {
 			t.Errorf("key is not ast.StringLiteral. got=%T", k)
@@ -908,7 +1811,8 @@ func TestParsingHashLiteralsBoolKeys(t *testing.T) {
 		false: 2,
 	}
 
-	for k, v := range hash.Pairs {
+	for _, pair := range hash.Pairs {
+		k, v := pair.Key, pair.Value
 		literal, ok := k.(*ast.Boolean)
 		if !ok {
 			t.Errorf("key is not ast.Boolean. got=%T", k)
@@ -952,7 +1856,8 @@ func TestParsingHashLiteralsIntegerKeys(t *testing.T) {
 		10: 10,
 	}
 
-	for k, v := range hash.Pairs {
+	for _, pair := range hash.Pairs {
+		k, v := pair.Key, pair.Value
 		literal, ok := k.(*ast.IntegerLiteral)
 		if !ok {
 			t.Errorf("key is not ast.IntegerLiteral. got=%T", k)
@@ -1017,7 +1922,8 @@ func TestParsingHashLiteralsWithExpressions(t *testing.T) {
 		},
 	}
 
-	for k, v := range hash.Pairs {
+	for _, pair := range hash.Pairs {
+		k, v := pair.Key, pair.Value
 		literal, ok := k.(*ast.StringLiteral)
 		if !ok {
 			t.Errorf("key is not ast.StringLiteral. got=%T", k)
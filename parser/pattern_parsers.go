@@ -0,0 +1,155 @@
+package parser
+
+import (
+	"fmt"
+
+	"git.tigh.dev/tigh-latte/monkeyscript/ast"
+	"git.tigh.dev/tigh-latte/monkeyscript/token"
+)
+
+func (p *Parser) parsePattern() ast.Pattern {
+	switch p.curToken.Type {
+	case token.IDENT:
+		ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		if p.peekToken.Type != token.COLON {
+			return ident
+		}
+
+		p.nextToken()
+		pattern := &ast.TypePattern{Token: p.curToken, Name: ident}
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		pattern.TypeName = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		return pattern
+	case token.INT, token.STRING, token.TRUE, token.FALSE, token.MINUS, token.NULL:
+		pattern := &ast.LiteralPattern{Token: p.curToken}
+		// Parse above RANGE so `1..10` is a single pattern.
+		if pattern.Value = p.parseExpression(LESSGREATER); pattern.Value == nil {
+			return nil
+		}
+		return pattern
+	case token.LSQUAR:
+		return p.parseArrayPattern()
+	case token.LSQUIG:
+		return p.parseHashPattern()
+	default:
+		p.errors = append(p.errors, fmt.Errorf("%w: unexpected %q", ErrInvalidPattern, p.curToken.Literal))
+		return nil
+	}
+}
+
+// parsePatternElement parses a pattern nested in an array or hash pattern,
+// which may be followed by `= default`.
+func (p *Parser) parsePatternElement() ast.Pattern {
+	pattern := p.parsePattern()
+	if pattern == nil {
+		return nil
+	}
+
+	return p.parsePatternDefault(pattern)
+}
+
+func (p *Parser) parsePatternDefault(pattern ast.Pattern) ast.Pattern {
+	if p.peekToken.Type != token.ASSIGN {
+		return pattern
+	}
+	p.nextToken()
+
+	def := &ast.DefaultPattern{Token: p.curToken, Pattern: pattern}
+	p.nextToken()
+	def.Default = p.parseExpression(ASSIGN)
+
+	return def
+}
+
+// parsePatternRest parses the `...name` which may close an array or hash
+// pattern.
+func (p *Parser) parsePatternRest(end token.TokenType) *ast.Identifier {
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	rest := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if p.peekToken.Type != end {
+		p.errors = append(p.errors, fmt.Errorf("%w: ...%s must come last", ErrInvalidPattern, rest))
+		return nil
+	}
+
+	return rest
+}
+
+func (p *Parser) parseArrayPattern() ast.Pattern {
+	pattern := &ast.ArrayPattern{Token: p.curToken}
+
+	for p.peekToken.Type != token.RSQUAR {
+		p.nextToken()
+
+		if p.curToken.Type == token.ELLIPSIS {
+			if pattern.Rest = p.parsePatternRest(token.RSQUAR); pattern.Rest == nil {
+				return nil
+			}
+			break
+		}
+
+		el := p.parsePatternElement()
+		if el == nil {
+			return nil
+		}
+		pattern.Elements = append(pattern.Elements, el)
+
+		if p.peekToken.Type != token.RSQUAR && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RSQUAR) {
+		return nil
+	}
+
+	return pattern
+}
+
+// parseHashPattern parses patterns such as `{amount, currency = "USD",
+// "first name": name, meta: {id}, ...rest}`.
+func (p *Parser) parseHashPattern() ast.Pattern {
+	pattern := &ast.HashPattern{Token: p.curToken}
+
+	for p.peekToken.Type != token.RSQUIG {
+		p.nextToken()
+
+		if p.curToken.Type == token.ELLIPSIS {
+			if pattern.Rest = p.parsePatternRest(token.RSQUIG); pattern.Rest == nil {
+				return nil
+			}
+			break
+		}
+
+		entry := &ast.HashPatternEntry{Key: p.curToken.Literal}
+		switch {
+		case p.peekToken.Type == token.COLON && (p.curToken.Type == token.IDENT || p.curToken.Type == token.STRING):
+			p.nextToken()
+			p.nextToken()
+			entry.Pattern = p.parsePatternElement()
+		case p.curToken.Type == token.IDENT:
+			entry.Pattern = p.parsePatternDefault(&ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+		default:
+			p.errors = append(p.errors, fmt.Errorf("%w: unexpected %q in hash pattern", ErrInvalidPattern, p.curToken.Literal))
+			return nil
+		}
+		if entry.Pattern == nil {
+			return nil
+		}
+		pattern.Entries = append(pattern.Entries, entry)
+
+		if p.peekToken.Type != token.RSQUIG && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RSQUIG) {
+		return nil
+	}
+
+	return pattern
+}
@@ -5,26 +5,39 @@ import "git.tigh.dev/tigh-latte/monkeyscript/token"
 const (
 	_ int = iota
 	LOWEST
+	ASSIGN      // x[i] = y
+	COALESCE    // a ?? b
 	EQUALS      // ==
 	LESSGREATER // > or <
+	PIPELINE    // x |> f()
+	RANGE       // 0..n
 	SUM         // +
 	PRODUCT     // *
 	PREFIX      // iX or !X
 	CALL        // myFunction(X)
-	INDEX       // array[index]
+	INDEX       // array[index] or hash.field
 )
 
 var precedences = map[token.TokenType]int{
-	token.EQ:       EQUALS,
-	token.NEQ:      EQUALS,
-	token.LT:       LESSGREATER,
-	token.GT:       LESSGREATER,
-	token.PLUS:     SUM,
-	token.MINUS:    SUM,
-	token.SLASH:    PRODUCT,
-	token.ASTERISK: PRODUCT,
-	token.LPAREN:   CALL,
-	token.LSQUAR:   INDEX,
+	token.ASSIGN:    ASSIGN,
+	token.COALESCE:  COALESCE,
+	token.EQ:        EQUALS,
+	token.NEQ:       EQUALS,
+	token.LT:        LESSGREATER,
+	token.GT:        LESSGREATER,
+	token.PIPELINE:  PIPELINE,
+	token.DOTDOT:    RANGE,
+	token.DOTDOTLT:  RANGE,
+	token.PLUS:      SUM,
+	token.MINUS:     SUM,
+	token.SLASH:     PRODUCT,
+	token.RATIO:     PRODUCT,
+	token.ASTERISK:  PRODUCT,
+	token.LPAREN:    CALL,
+	token.LSQUAR:    INDEX,
+	token.DOT:       INDEX,
+	token.OPTDOT:    INDEX,
+	token.OPTLSQUAR: INDEX,
 }
 
 func (p *Parser) peekPrecedence() int {
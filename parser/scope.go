@@ -0,0 +1,82 @@
+package parser
+
+import (
+	"fmt"
+
+	"git.tigh.dev/tigh-latte/monkeyscript/ast"
+)
+
+// scope records the names declared in one runtime scope, and whether each is
+// a constant, so writes to constants are rejected before the program runs.
+// Scopes follow the evaluator's: every block gets its own, nested within one
+// holding the parameters of a function, the variables of a for loop or the
+// names bound by a match arm's pattern.
+//
+// A name can be declared again with `let` in the same scope, replacing it,
+// unless either declaration is a constant. Any name, constants included, may
+// be shadowed in an inner scope.
+type scope struct {
+	parent *scope
+	names  map[string]bool
+}
+
+func (p *Parser) pushScope() {
+	p.scope = &scope{parent: p.scope, names: make(map[string]bool)}
+}
+
+func (p *Parser) popScope() {
+	p.scope = p.scope.parent
+}
+
+// declare records ident in the current scope. A constant can be neither
+// redeclared nor declared over an existing name in the same scope.
+func (p *Parser) declare(ident *ast.Identifier, constant bool) {
+	if ident.Value == "_" {
+		return
+	}
+
+	isConst, declared := p.scope.names[ident.Value]
+	if isConst || declared && constant {
+		p.errors = append(p.errors, fmt.Errorf("%w: %s", ErrConstRedeclared, ident))
+		return
+	}
+	p.scope.names[ident.Value] = constant
+}
+
+// declarePattern declares every name bound by pattern.
+func (p *Parser) declarePattern(pattern ast.Pattern) {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		p.declare(pattern, false)
+	case *ast.TypePattern:
+		p.declare(pattern.Name, false)
+	case *ast.DefaultPattern:
+		p.declarePattern(pattern.Pattern)
+	case *ast.ArrayPattern:
+		for _, elem := range pattern.Elements {
+			p.declarePattern(elem)
+		}
+		if pattern.Rest != nil {
+			p.declare(pattern.Rest, false)
+		}
+	case *ast.HashPattern:
+		for _, entry := range pattern.Entries {
+			p.declarePattern(entry.Pattern)
+		}
+		if pattern.Rest != nil {
+			p.declare(pattern.Rest, false)
+		}
+	}
+}
+
+// checkAssign reports an error if ident resolves to a constant.
+func (p *Parser) checkAssign(ident *ast.Identifier) {
+	for s := p.scope; s != nil; s = s.parent {
+		if isConst, ok := s.names[ident.Value]; ok {
+			if isConst {
+				p.errors = append(p.errors, fmt.Errorf("%w: %s", ErrConstAssign, ident))
+			}
+			return
+		}
+	}
+}
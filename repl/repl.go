@@ -33,11 +33,21 @@ func Start(in io.Reader, out io.Writer) {
 			fmt.Println(p.Errors())
 			continue
 		}
+		if p.Warnings() != nil {
+			fmt.Fprintln(out, "warning:", p.Warnings())
+		}
 
-		evaluated := evaluator.Eval(program, env)
-		if evaluated == nil {
+		// An empty line ends the session.
+		if len(program.Statements) == 0 {
 			return
 		}
+
+		evaluated := evaluator.Eval(program, env)
+		if err, ok := evaluated.(*object.Error); ok {
+			io.WriteString(out, "ERROR: "+err.StackTrace())
+			io.WriteString(out, "\n")
+			continue
+		}
 		io.WriteString(out, evaluated.Inspect())
 		io.WriteString(out, "\n")
 	}
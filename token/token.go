@@ -1,14 +1,32 @@
 package token
 
+import "strconv"
+
 type (
 	TokenType string
 
 	Token struct {
 		Type    TokenType
 		Literal string
+		Pos     Position
+	}
+
+	// Position is the 1-based line and column at which a token starts.
+	Position struct {
+		Line   int
+		Column int
 	}
 )
 
+func (p Position) String() string {
+	return strconv.Itoa(p.Line) + ":" + strconv.Itoa(p.Column)
+}
+
+// IsValid reports whether the position was set by the lexer.
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
 const (
 	ILLEGAL = "ILLEGAL"
 	EOF     = "EOF"
@@ -22,6 +40,7 @@ const (
 	EXCLAIM  = "!"
 	ASTERISK = "*"
 	SLASH    = "/"
+	RATIO    = "//"
 
 	EQ  = "=="
 	NEQ = "!="
@@ -31,6 +50,16 @@ const (
 	COMMA     = ","
 	SEMICOLON = ";"
 	COLON     = ":"
+	DOT       = "."
+	DOTDOT    = ".."
+	DOTDOTLT  = "..<"
+	ELLIPSIS  = "..."
+	FATARROW  = "=>"
+	PIPE      = "|"
+	PIPELINE  = "|>"
+	OPTDOT    = "?."
+	OPTLSQUAR = "?["
+	COALESCE  = "??"
 
 	LPAREN = "("
 	RPAREN = ")"
@@ -41,23 +70,55 @@ const (
 
 	FUNCTION = "FUNCTION"
 	LET      = "LET"
+	CONST    = "CONST"
 	IF       = "IF"
 	ELSE     = "ELSE"
 	TRUE     = "TRUE"
 	FALSE    = "FALSE"
 	RETURN   = "RETURN"
+	WHILE    = "WHILE"
+	BREAK    = "BREAK"
+	CONTINUE = "CONTINUE"
+	FOR      = "FOR"
+	IN       = "IN"
+	MATCH    = "MATCH"
+	NULL     = "NULL"
+	THROW    = "THROW"
+	TRY      = "TRY"
+	CATCH    = "CATCH"
+	FINALLY  = "FINALLY"
 
 	STRING = "STRING"
+
+	// An interpolated string such as `"a${x}b${y:.2f}c"` is lexed as
+	// INTERPSTART "a", the tokens of x, INTERPMID "b", the tokens of y,
+	// FORMATSPEC ".2f", then INTERPEND "c".
+	INTERPSTART = "INTERPSTART"
+	INTERPMID   = "INTERPMID"
+	INTERPEND   = "INTERPEND"
+	FORMATSPEC  = "FORMATSPEC"
 )
 
 var keywords = map[string]TokenType{
-	"fn":     FUNCTION,
-	"let":    LET,
-	"if":     IF,
-	"else":   ELSE,
-	"return": RETURN,
-	"true":   TRUE,
-	"false":  FALSE,
+	"fn":       FUNCTION,
+	"let":      LET,
+	"const":    CONST,
+	"if":       IF,
+	"else":     ELSE,
+	"return":   RETURN,
+	"true":     TRUE,
+	"false":    FALSE,
+	"while":    WHILE,
+	"break":    BREAK,
+	"continue": CONTINUE,
+	"for":      FOR,
+	"in":       IN,
+	"match":    MATCH,
+	"null":     NULL,
+	"throw":    THROW,
+	"try":      TRY,
+	"catch":    CATCH,
+	"finally":  FINALLY,
 }
 
 func LookupIdent(ident string) TokenType {